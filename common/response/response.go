@@ -17,6 +17,8 @@ const (
 	ERROR           = 500
 	SUCCESS         = 200
 	UNAUTHORIZATION = 401
+	NOT_FOUND       = 404
+	CONFLICT        = 409
 )
 
 func SuccessResult(code int, data interface{}, msg string, c *gin.Context) {
@@ -43,6 +45,22 @@ func InternalErrorResult(code int, data interface{}, msg string, c *gin.Context)
 	})
 }
 
+func NotFoundResult(code int, data interface{}, msg string, c *gin.Context) {
+	c.IndentedJSON(http.StatusNotFound, Response{
+		code,
+		data,
+		msg,
+	})
+}
+
+func ConflictResult(code int, data interface{}, msg string, c *gin.Context) {
+	c.IndentedJSON(http.StatusConflict, Response{
+		code,
+		data,
+		msg,
+	})
+}
+
 func NoAuth(message string, c *gin.Context) {
 	c.IndentedJSON(http.StatusUnauthorized, Response{
 		UNAUTHORIZATION,
@@ -74,3 +92,11 @@ func ValidationFailureWithMessage(message string, c *gin.Context) {
 func InteralErrorWithMessage(message string, c *gin.Context) {
 	ValidationFailureResult(ERROR, map[string]interface{}{}, message, c)
 }
+
+func NotFoundWithMessage(message string, c *gin.Context) {
+	NotFoundResult(NOT_FOUND, map[string]interface{}{}, message, c)
+}
+
+func ConflictWithMessage(message string, c *gin.Context) {
+	ConflictResult(CONFLICT, map[string]interface{}{}, message, c)
+}
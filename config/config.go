@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Database struct {
+	// URL is a full connection string such as DATABASE_URL. When set it takes
+	// precedence over the individual connection fields below.
+	URL             string        `yaml:"url"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslMode"`
+	MaxOpenConns    int           `yaml:"maxOpenConns"`
+	MaxIdleConns    int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+	// ConnectTimeout bounds how long startup keeps retrying to reach the database.
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+	MigrationsDir  string        `yaml:"migrationsDir"`
+}
+
+// Graph store backends selected by Config.Store.
+const (
+	StorePostgres = "postgres"
+	StoreSQLite   = "sqlite"
+	StoreMemory   = "memory"
+)
+
+type Config struct {
+	// Store selects where graphs are kept: StorePostgres, StoreSQLite or StoreMemory.
+	Store         string   `yaml:"store"`
+	SQLitePath    string   `yaml:"sqlitePath"`
+	Database      Database `yaml:"database"`
+	ListenAddr    string   `yaml:"listenAddr"`
+	GraphFile     string   `yaml:"graphFile"`
+	CORSOrigins   []string `yaml:"corsOrigins"`
+	RetentionDays int      `yaml:"retentionDays"`
+	// MaxPaths caps the paths returned per all-paths answer page.
+	MaxPaths int `yaml:"maxPaths"`
+	// QueryTimeout bounds the search time of a single path query, 0 disables it.
+	QueryTimeout time.Duration `yaml:"queryTimeout"`
+	// CacheSize is the most graphs whose query indexes are kept in memory.
+	CacheSize int `yaml:"cacheSize"`
+}
+
+// Default returns the configuration used when nothing else is given. It matches
+// the services in docker-compose.yml.
+func Default() Config {
+	return Config{
+		Store:      StorePostgres,
+		SQLitePath: "data/graphs.db",
+		Database: Database{
+			Host:            "db",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "pwd",
+			Name:            "mydb",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:  time.Minute,
+			MigrationsDir:   "./migrations",
+		},
+		ListenAddr:   ":8080",
+		GraphFile:    "data/exampleTest.xml",
+		CORSOrigins:  []string{"*"},
+		MaxPaths:     1000,
+		QueryTimeout: 10 * time.Second,
+		CacheSize:    100,
+	}
+}
+
+// DSN returns the connection string for lib/pq.
+func (d Database) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=%s", d.Host, d.Port, d.User, d.Name, d.Password, d.SSLMode)
+}
+
+// Validate reports every invalid setting at once. Database settings are only
+// checked when the Postgres store is selected.
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Store {
+	case StorePostgres:
+		errs = append(errs, c.Database.validate()...)
+	case StoreSQLite:
+		if c.SQLitePath == "" {
+			errs = append(errs, errors.New("sqlite path must be set"))
+		}
+	case StoreMemory:
+	default:
+		errs = append(errs, fmt.Errorf("store %q is not one of postgres, sqlite or memory", c.Store))
+	}
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("listen address %q is invalid: %v", c.ListenAddr, err))
+	}
+	if c.GraphFile == "" {
+		errs = append(errs, errors.New("graph file must be set"))
+	}
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("at least one CORS origin must be set"))
+	}
+	if c.RetentionDays < 0 {
+		errs = append(errs, errors.New("retention days must not be negative"))
+	}
+	if c.MaxPaths <= 0 {
+		errs = append(errs, errors.New("max paths must be positive"))
+	}
+	if c.QueryTimeout < 0 {
+		errs = append(errs, errors.New("query timeout must not be negative"))
+	}
+	if c.CacheSize <= 0 {
+		errs = append(errs, errors.New("cache size must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+func (d Database) validate() []error {
+	var errs []error
+	if d.URL != "" {
+		if u, err := url.Parse(d.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = append(errs, errors.New("database url must be a postgres:// url"))
+		}
+	} else {
+		if d.Host == "" {
+			errs = append(errs, errors.New("database host must be set"))
+		}
+		if d.Port <= 0 || d.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database port %d is out of range", d.Port))
+		}
+		if d.User == "" || d.Name == "" {
+			errs = append(errs, errors.New("database user and name must be set"))
+		}
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 || d.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database pool settings must not be negative"))
+	}
+	if d.ConnectTimeout <= 0 {
+		errs = append(errs, errors.New("database connect timeout must be positive"))
+	}
+	if d.MigrationsDir == "" {
+		errs = append(errs, errors.New("migrations directory must be set"))
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		errs = append(errs, errors.New("database max idle connections must not exceed max open connections"))
+	}
+	return errs
+}
+
+// setting binds one configuration value to its command line flag and environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"store", "STORE", "graph store backend: postgres, sqlite or memory", func(c *Config, v string) error { c.Store = v; return nil }},
+	{"sqlite-path", "SQLITE_PATH", "database file of the sqlite store", func(c *Config, v string) error { c.SQLitePath = v; return nil }},
+	{"db-url", "DATABASE_URL", "postgres connection url, overrides the other db settings", func(c *Config, v string) error { c.Database.URL = v; return nil }},
+	{"db-host", "DB_HOST", "postgres host", func(c *Config, v string) error { c.Database.Host = v; return nil }},
+	{"db-port", "DB_PORT", "postgres port", func(c *Config, v string) error { return setInt(&c.Database.Port, v) }},
+	{"db-user", "DB_USER", "postgres user", func(c *Config, v string) error { c.Database.User = v; return nil }},
+	{"db-password", "DB_PASSWORD", "postgres password", func(c *Config, v string) error { c.Database.Password = v; return nil }},
+	{"db-name", "DB_NAME", "postgres database name", func(c *Config, v string) error { c.Database.Name = v; return nil }},
+	{"db-sslmode", "DB_SSLMODE", "postgres sslmode", func(c *Config, v string) error { c.Database.SSLMode = v; return nil }},
+	{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections, 0 is unlimited", func(c *Config, v string) error { return setInt(&c.Database.MaxOpenConns, v) }},
+	{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", func(c *Config, v string) error { return setInt(&c.Database.MaxIdleConns, v) }},
+	{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection, e.g. 30m", func(c *Config, v string) error { return setDuration(&c.Database.ConnMaxLifetime, v) }},
+	{"db-connect-timeout", "DB_CONNECT_TIMEOUT", "how long to retry connecting to the database at startup, e.g. 1m", func(c *Config, v string) error { return setDuration(&c.Database.ConnectTimeout, v) }},
+	{"migrations-dir", "MIGRATIONS_DIR", "directory with the database migrations", func(c *Config, v string) error { c.Database.MigrationsDir = v; return nil }},
+	{"listen", "LISTEN_ADDR", "http listen address", func(c *Config, v string) error { c.ListenAddr = v; return nil }},
+	{"graph-file", "GRAPH_FILE", "graph XML imported at startup as the default graph", func(c *Config, v string) error { c.GraphFile = v; return nil }},
+	{"cors-origins", "CORS_ORIGINS", "comma separated allowed CORS origins, * allows all", func(c *Config, v string) error { c.CORSOrigins = splitList(v); return nil }},
+	{"retention-days", "RETENTION_DAYS", "purge graphs unused for this many days, 0 disables", func(c *Config, v string) error { return setInt(&c.RetentionDays, v) }},
+	{"max-paths", "MAX_PATHS", "most paths returned per all-paths answer page", func(c *Config, v string) error { return setInt(&c.MaxPaths, v) }},
+	{"query-timeout", "QUERY_TIMEOUT", "search time limit of a single path query, e.g. 10s, 0 disables", func(c *Config, v string) error { return setDuration(&c.QueryTimeout, v) }},
+	{"cache-size", "CACHE_SIZE", "most graphs whose query indexes are kept in memory", func(c *Config, v string) error { return setInt(&c.CacheSize, v) }},
+}
+
+// Load builds the configuration from, in increasing order of precedence: the
+// defaults, the YAML file given by -config or CONFIG_FILE, environment
+// variables and command line flags. The result is validated.
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("tucowDemo", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "optional YAML configuration file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.flag] = fs.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("error reading config file: %v", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("error parsing config file %s: %v", *configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %v", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.set(&cfg, *flagValues[s.flag]); err != nil {
+					flagErr = fmt.Errorf("invalid -%s: %v", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*dst = d
+	return nil
+}
+
+func splitList(v string) []string {
+	result := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, "host=db port=5432 user=postgres dbname=mydb password=pwd sslmode=disable", cfg.Database.DSN())
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+listenAddr: ":9000"
+graphFile: data/other.xml
+corsOrigins: ["https://a.example"]
+database:
+  host: filehost
+  maxOpenConns: 20
+  connMaxLifetime: 5m
+`), 0o600)
+	assert.NoError(t, err)
+
+	t.Setenv("LISTEN_ADDR", ":9100")
+	t.Setenv("DB_HOST", "envhost")
+	t.Setenv("QUERY_TIMEOUT", "250ms")
+
+	cfg, err := Load([]string{"-config", file, "-db-host", "flaghost", "-cors-origins", "https://b.example, https://c.example"})
+	assert.NoError(t, err)
+	assert.Equal(t, ":9100", cfg.ListenAddr)
+	assert.Equal(t, "data/other.xml", cfg.GraphFile)
+	assert.Equal(t, "flaghost", cfg.Database.Host)
+	assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 5*time.Minute, cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, 250*time.Millisecond, cfg.QueryTimeout)
+	assert.Equal(t, []string{"https://b.example", "https://c.example"}, cfg.CORSOrigins)
+}
+
+func TestLoad_DatabaseURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://postgres:pwd@db:5432/mydb?sslmode=disable")
+
+	cfg, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://postgres:pwd@db:5432/mydb?sslmode=disable", cfg.Database.DSN())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("DB_PORT", "70000")
+
+	_, err := Load([]string{"-listen", "8080", "-retention-days", "-1", "-query-timeout", "-1s", "-cache-size", "0"})
+	assert.Error(t, err)
+	for _, msg := range []string{"port 70000", "listen address", "retention days", "query timeout", "cache size"} {
+		assert.True(t, strings.Contains(err.Error(), msg), "expected %q in %v", msg, err)
+	}
+}
+
+func TestLoad_BadNumber(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "DB_MAX_OPEN_CONNS")
+}
+
+func TestLoad_Store(t *testing.T) {
+	// database settings do not matter without Postgres
+	t.Setenv("DB_PORT", "70000")
+
+	cfg, err := Load([]string{"-store", "sqlite", "-sqlite-path", "graphs.db"})
+	assert.NoError(t, err)
+	assert.Equal(t, StoreSQLite, cfg.Store)
+	assert.Equal(t, "graphs.db", cfg.SQLitePath)
+
+	_, err = Load([]string{"-store", "mysql"})
+	assert.ErrorContains(t, err, `store "mysql"`)
+}
@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"time"
 
+	"github.com/GuohaoMa/tucowDemo/config"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -11,32 +14,67 @@ import (
 	"github.com/pkg/errors"
 )
 
-var Db *sql.DB
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// Database owns the connection pool of the service.
+type Database struct {
+	Db *sql.DB
+}
 
-func init() {
-	var err error
-	Db, err = sql.Open("postgres", "host=db port=5432 user=postgres dbname=mydb password=pwd sslmode=disable")
+// Open connects to Postgres with the given settings. Postgres often accepts
+// connections only some time after its container started, so the connection is
+// retried with exponential backoff until it succeeds or ctx is done.
+func Open(ctx context.Context, cfg config.Database) (*Database, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
-		panic(err)
+		return nil, errors.Wrap(err, "error opening database")
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return &Database{Db: db}, nil
+		}
+		log.Printf("database not ready (attempt %d): %v, retrying in %v", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, errors.Wrapf(err, "error connecting to database after %d attempts", attempt)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
 	}
+}
 
-	driver, err := postgres.WithInstance(Db, &postgres.Config{})
+// Migrate applies the pending migrations found in dir.
+func (d *Database) Migrate(dir string) error {
+	driver, err := postgres.WithInstance(d.Db, &postgres.Config{})
 	if err != nil {
-		panic(errors.Wrap(err, "error creating driver: %w"))
+		return errors.Wrap(err, "error creating driver")
 	}
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://./migrations",
-		"postgres",
-		driver)
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "postgres", driver)
 	if err != nil {
-		panic(errors.Wrap(err, "error while migrating database: %w"))
+		return errors.Wrap(err, "error while migrating database")
 	}
 
 	if err = m.Up(); err != nil {
-		if err.Error() == "no change" {
+		if errors.Is(err, migrate.ErrNoChange) {
 			log.Println("no change made by migrations")
-		} else {
-			panic(errors.Wrap(err, "error while migrating database: %w"))
+			return nil
 		}
+		return errors.Wrap(err, "error while migrating database")
 	}
+	return nil
+}
+
+// Close closes the connection pool.
+func (d *Database) Close() error {
+	return d.Db.Close()
 }
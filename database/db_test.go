@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GuohaoMa/tucowDemo/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_GivesUpWhenContextEnds(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	db, err := Open(ctx, cfg)
+	assert.Nil(t, db)
+	assert.ErrorContains(t, err, "error connecting to database after")
+	// at least one retry happened before the deadline
+	assert.GreaterOrEqual(t, time.Since(start), initialBackoff)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+)
+
+// foundPath is a path found by findAllPaths with the arcs it traverses.
+type foundPath struct {
+	nodes []string
+	arcs  []model.Arc
+	cost  float64
+	// position holds the index of every traversed arc in the adjacency list of
+	// its start node. It locates the path in the search order for cursors.
+	position []int
+}
+
+func (p foundPath) detail() PathDetail {
+	d := PathDetail{Cost: p.cost, Edges: make([]PathEdge, len(p.arcs))}
+	for i, a := range p.arcs {
+		d.Edges[i] = PathEdge{Id: a.Edge, Cost: a.Cost}
+	}
+	return d
+}
+
+// pathBounds restricts the paths enumerated by findAllPaths. Zero values and a
+// nil maxCost do not restrict anything.
+type pathBounds struct {
+	maxDepth int
+	maxCost  *float64
+}
+
+// pathSearch is the state of one findAllPaths run.
+type pathSearch struct {
+	graphMap map[string][]model.Arc
+	end      string
+	bounds   pathBounds
+	// after is the position of the path the search resumes after, if any.
+	after []int
+	visit func(p foundPath) bool
+	stop  *stopper
+
+	path     []string
+	arcs     []model.Arc
+	position []int
+	visited  map[string]bool
+	stopped  bool
+}
+
+// findAllPaths enumerates every simple path from start to end within bounds with
+// a backtracking dfs and passes each one to visit until visit returns false. The
+// passed path is a copy. Paths come in a fixed order for a given graphMap; when
+// after is set, the search resumes behind the path found at that position.
+// Once ctx is done the search stops and returns the context error.
+func findAllPaths(ctx context.Context, start string, end string, graphMap map[string][]model.Arc, bounds pathBounds, after []int, visit func(p foundPath) bool) error {
+	s := &pathSearch{
+		graphMap: graphMap,
+		end:      end,
+		bounds:   bounds,
+		after:    after,
+		visit:    visit,
+		stop:     &stopper{ctx: ctx},
+		path:     []string{start},
+		visited:  map[string]bool{start: true},
+	}
+	s.walk(start, 0, after != nil)
+	return s.stop.err
+}
+
+// walk extends the current path from cur, which was reached at the given cost.
+// resuming is set while the current path is a prefix of s.after.
+func (s *pathSearch) walk(cur string, cost float64, resuming bool) {
+	if s.stop.stopped() {
+		s.stopped = true
+		return
+	}
+	if cur == s.end {
+		// the path at s.after itself was already returned
+		if resuming {
+			return
+		}
+		p := foundPath{
+			nodes:    append([]string{}, s.path...),
+			arcs:     append([]model.Arc{}, s.arcs...),
+			cost:     cost,
+			position: append([]int{}, s.position...),
+		}
+		s.stopped = !s.visit(p)
+		return
+	}
+	if s.bounds.maxDepth > 0 && len(s.arcs) >= s.bounds.maxDepth {
+		return
+	}
+
+	depth := len(s.arcs)
+	first := 0
+	if resuming {
+		if depth >= len(s.after) {
+			return
+		}
+		first = s.after[depth]
+	}
+	for i := first; i < len(s.graphMap[cur]) && !s.stopped; i++ {
+		next := s.graphMap[cur][i]
+		if s.visited[next.To] {
+			continue
+		}
+		if s.bounds.maxCost != nil && cost+next.Cost > *s.bounds.maxCost {
+			continue
+		}
+		s.visited[next.To] = true
+		s.path, s.arcs, s.position = append(s.path, next.To), append(s.arcs, next), append(s.position, i)
+		s.walk(next.To, cost+next.Cost, resuming && i == first)
+		s.path, s.arcs, s.position = s.path[:len(s.path)-1], s.arcs[:len(s.arcs)-1], s.position[:len(s.position)-1]
+		delete(s.visited, next.To)
+	}
+}
+
+// pathCursor is the decoded form of the cursor returned with a page of paths.
+// Pages in search order resume after a path position, pages sorted by cost
+// continue at an offset into the sorted result. Positions and offsets only mean
+// something for the query and index they were found with, so the cursor carries
+// the graph id, a hash of the query and the index version.
+type pathCursor struct {
+	Graph   int    `json:"g"`
+	Query   string `json:"q"`
+	Version string `json:"v"`
+	After   []int  `json:"a,omitempty"`
+	Offset  int    `json:"o,omitempty"`
+}
+
+var (
+	errInvalidCursor = errors.New("Invalid cursor.")
+	errCursorScope   = errors.New("The cursor belongs to a different query or graph version.")
+)
+
+// newPathCursor returns the cursor of the first page of q on ix.
+func newPathCursor(ix *model.Index, q PathRq) pathCursor {
+	return pathCursor{Graph: ix.Graph.Id, Query: queryHash(q), Version: ix.Version}
+}
+
+// queryHash hashes the parameters of q that decide which paths are found and in
+// which order. The page size may change from page to page.
+func queryHash(q PathRq) string {
+	maxCost := "-"
+	if q.MaxCost != nil {
+		maxCost = strconv.FormatFloat(*q.MaxCost, 'g', -1, 64)
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%q %q %d %s %q", q.Start, q.End, q.MaxDepth, maxCost, q.SortBy)))
+	return hex.EncodeToString(h[:8])
+}
+
+// resumeAfter returns c encoded to continue after the path at position.
+func (c pathCursor) resumeAfter(position []int) string {
+	c.After, c.Offset = position, 0
+	return c.encode()
+}
+
+// resumeAt returns c encoded to continue at offset into sorted paths.
+func (c pathCursor) resumeAt(offset int) string {
+	c.After, c.Offset = nil, offset
+	return c.encode()
+}
+
+func (c pathCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePathCursor parses a cursor and checks that it was issued with the graph,
+// query and index version of scope.
+func decodePathCursor(s string, scope pathCursor) (pathCursor, error) {
+	c := pathCursor{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Offset < 0 {
+		return c, errInvalidCursor
+	}
+	if c.Graph != scope.Graph || c.Query != scope.Query || c.Version != scope.Version {
+		return c, errCursorScope
+	}
+	for _, i := range c.After {
+		if i < 0 {
+			return c, errInvalidCursor
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// layeredGraph joins every node of a layer to every node of the next one, so
+// there are width^(layers-2) paths from the single first to the single last node.
+func layeredGraph(layers int, width int) map[string][]model.Arc {
+	name := func(l, i int) string { return strconv.Itoa(l) + "-" + strconv.Itoa(i) }
+	graphMap := map[string][]model.Arc{}
+	for l := 0; l < layers-1; l++ {
+		from, to := width, width
+		if l == 0 {
+			from = 1
+		}
+		if l == layers-2 {
+			to = 1
+		}
+		for i := 0; i < from; i++ {
+			for j := 0; j < to; j++ {
+				graphMap[name(l, i)] = append(graphMap[name(l, i)], model.Arc{Edge: name(l, i) + ">" + name(l+1, j), To: name(l+1, j), Cost: float64(j + 1)})
+			}
+		}
+	}
+	return graphMap
+}
+
+func collectPaths(graphMap map[string][]model.Arc, bounds pathBounds, after []int, max int) []foundPath {
+	result := []foundPath{}
+	findAllPaths(context.Background(), "0-0", "4-0", graphMap, bounds, after, func(p foundPath) bool {
+		result = append(result, p)
+		return len(result) < max
+	})
+	return result
+}
+
+func TestFindAllPaths_ResumesAfterPosition(t *testing.T) {
+	graphMap := layeredGraph(5, 3)
+	all := collectPaths(graphMap, pathBounds{}, nil, 1000)
+	assert.Len(t, all, 27)
+
+	var paged []string
+	var after []int
+	for pages := 0; ; pages++ {
+		page := collectPaths(graphMap, pathBounds{}, after, 5)
+		for _, p := range page {
+			paged = append(paged, strings.Join(p.nodes, ","))
+		}
+		if len(page) < 5 {
+			break
+		}
+		after = page[len(page)-1].position
+		assert.Less(t, pages, 10)
+	}
+	expected := make([]string, len(all))
+	for i, p := range all {
+		expected[i] = strings.Join(p.nodes, ",")
+	}
+	assert.Equal(t, expected, paged)
+}
+
+func TestFindAllPaths_Bounds(t *testing.T) {
+	graphMap := layeredGraph(5, 3)
+	assert.Empty(t, collectPaths(graphMap, pathBounds{maxDepth: 3}, nil, 1000))
+	assert.Len(t, collectPaths(graphMap, pathBounds{maxDepth: 4}, nil, 1000), 27)
+
+	// every path costs 1 + (1..3) + (1..3) + 1, only the cheapest costs 4
+	maxCost := 4.0
+	cheap := collectPaths(graphMap, pathBounds{maxCost: &maxCost}, nil, 1000)
+	assert.Len(t, cheap, 1)
+	assert.Equal(t, 4.0, cheap[0].cost)
+}
+
+func TestFindAllPaths_Deadline(t *testing.T) {
+	// 20^8 paths, far more than can be enumerated before the deadline
+	graphMap := layeredGraph(10, 20)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	found := 0
+	err := findAllPaths(ctx, "0-0", "9-0", graphMap, pathBounds{}, nil, func(p foundPath) bool {
+		found++
+		return true
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Greater(t, found, 0)
+}
+
+func TestPathCursor(t *testing.T) {
+	scope := pathCursor{Graph: 3, Query: queryHash(PathRq{Start: "a", End: "b"}), Version: "v1"}
+	decoded, err := decodePathCursor(scope.resumeAfter([]int{0, 2, 1}), scope)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2, 1}, decoded.After)
+
+	other := scope
+	other.Graph = 4
+	_, err = decodePathCursor(scope.resumeAt(2), other)
+	assert.ErrorIs(t, err, errCursorScope)
+	other = scope
+	other.Version = "v2"
+	_, err = decodePathCursor(scope.resumeAt(2), other)
+	assert.ErrorIs(t, err, errCursorScope)
+	_, err = decodePathCursor("not a cursor", scope)
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
+
+func TestQueryHash(t *testing.T) {
+	cost := 2.0
+	q := PathRq{Start: "a", End: "b", MaxDepth: 3, MaxCost: &cost, SortBy: "cost"}
+	// the page size and the cursor itself do not change the query
+	assert.Equal(t, queryHash(q), queryHash(PathRq{Start: "a", End: "b", MaxDepth: 3, MaxCost: &cost, SortBy: "cost", MaxPaths: 5, Cursor: "x"}))
+	for _, changed := range []PathRq{
+		{Start: "a", End: "c", MaxDepth: 3, MaxCost: &cost, SortBy: "cost"},
+		{Start: "a", End: "b", MaxDepth: 4, MaxCost: &cost, SortBy: "cost"},
+		{Start: "a", End: "b", MaxDepth: 3, SortBy: "cost"},
+		{Start: "a", End: "b", MaxDepth: 3, MaxCost: &cost},
+	} {
+		assert.NotEqual(t, queryHash(q), queryHash(changed))
+	}
+}
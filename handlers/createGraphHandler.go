@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/GuohaoMa/tucowDemo/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateGraphRs struct {
+	Id       int  `json:"id"`
+	Revision int  `json:"revision"`
+	Created  bool `json:"created"`
+}
+
+// CreateGraphHandler validates the XML graph in the request body and persists it.
+// A graph whose identity is already stored becomes its next revision, unless the
+// content is unchanged, in which case the latest revision is returned as is.
+func CreateGraphHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			response.ValidationFailureWithMessage("Failed to read request body.", c)
+			return
+		}
+
+		if err := validation.Validate(bytes.NewReader(body)); err != nil {
+			var report *validation.Report
+			if errors.As(err, &report) {
+				response.ValidationFailureResult(response.BAD_REQUEST, report, "Invalid graph XML.", c)
+				return
+			}
+			response.ValidationFailureWithMessage(err.Error(), c)
+			return
+		}
+
+		graph := model.Graph{}
+		if err := xml.Unmarshal(body, &graph); err != nil {
+			response.ValidationFailureWithMessage("XML decoding failure.", c)
+			return
+		}
+
+		created, err := store.Import(&graph)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+
+		response.OkWithData(CreateGraphRs{Id: graph.Id, Revision: graph.Revision, Created: created}, c)
+	}
+}
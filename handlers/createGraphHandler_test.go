@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/GuohaoMa/tucowDemo/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const validGraphXML = `<graph>
+	<id>g1</id>
+	<name>Uploaded</name>
+	<nodes>
+		<node><id>a</id><name>A</name></node>
+		<node><id>b</id><name>B</name></node>
+	</nodes>
+	<edges>
+		<node><id>e1</id><from>a</from><to>b</to><cost>2</cost></node>
+	</edges>
+</graph>`
+
+func graphHash(t *testing.T, body string) string {
+	t.Helper()
+	graph := model.Graph{}
+	assert.NoError(t, xml.Unmarshal([]byte(body), &graph))
+	return graph.Hash()
+}
+
+// expectReplace registers the statements removing the nodes and edges of the
+// earlier revisions of graph g1 from revision 1, whose id is 7.
+func expectReplace(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("update node set removed_in").
+		WithArgs(1, "g1", 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("update edge set removed_in").
+		WithArgs(1, "g1", 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestCreateGraphHandler_Success(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select id, revision, content_hash from graph where identity").
+		WithArgs("g1").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph").
+		WithArgs("g1", "Uploaded", sqlmock.AnyArg(), "g1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(7, 1, time.Now()))
+	expectReplace(mock)
+	mock.ExpectQuery("insert into node").
+		WithArgs("a", "A", 7, "b", "B", 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("insert into edge").
+		WithArgs("e1", 1, "a", 2, "b", 2.0, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "e1"))
+	mock.ExpectCommit()
+
+	router := gin.Default()
+	router.POST("/graphs", CreateGraphHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs", strings.NewReader(validGraphXML))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/xml")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs struct {
+		response.Response
+		Data CreateGraphRs `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, rs.Data.Id)
+	assert.Equal(t, 1, rs.Data.Revision)
+	assert.True(t, rs.Data.Created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGraphHandler_InvalidXML(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	router := gin.Default()
+	router.POST("/graphs", CreateGraphHandler(model.NewPostgresStore(db)))
+
+	body := `<graph><id>g1</id><name>Bad</name><nodes></nodes></graph>`
+	req, err := http.NewRequest(http.MethodPost, "/graphs", strings.NewReader(body))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var rs struct {
+		Data validation.Report `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Len(t, rs.Data.Violations, 1)
+	assert.Equal(t, "There must be at least one <node> in the <nodes> group", rs.Data.Violations[0].Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGraphHandler_ExistingGraph(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select id, revision, content_hash from graph where identity").
+		WithArgs("g1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "content_hash"}).AddRow(5, 2, graphHash(t, validGraphXML)))
+	mock.ExpectExec("update graph set last_used_at").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	router := gin.Default()
+	router.POST("/graphs", CreateGraphHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs", strings.NewReader(validGraphXML))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs struct {
+		Data CreateGraphRs `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, rs.Data.Id)
+	assert.Equal(t, 2, rs.Data.Revision)
+	assert.False(t, rs.Data.Created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGraphHandler_Conflict(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select id, revision, content_hash from graph where identity").
+		WithArgs("g1").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(7, 1, time.Now()))
+	expectReplace(mock)
+	mock.ExpectQuery("insert into node").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("insert into edge").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "edge_key"})
+	mock.ExpectRollback()
+
+	router := gin.Default()
+	router.POST("/graphs", CreateGraphHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs", strings.NewReader(validGraphXML))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var rs response.Response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, model.ErrDuplicateEdge.Error(), rs.Msg)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCycleLimit = 100
+	maxCycleLimit     = 10000
+)
+
+type CyclesRs struct {
+	Cycles [][]string `json:"cycles"`
+	// Truncated is set when the graph has more cycles than the limit.
+	Truncated bool `json:"truncated"`
+}
+
+// CyclesHandler lists the elementary cycles of graph :id, each as the node ids in
+// edge order. ?limit= caps the number of cycles and ?revision= selects another
+// revision of the graph.
+func CyclesHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		graphId, ok = resolveRevision(c, store, graphId)
+		if !ok {
+			return
+		}
+		limit := defaultCycleLimit
+		if l := c.Query("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 || n > maxCycleLimit {
+				response.ValidationFailureWithMessage("Limit must be between 1 and "+strconv.Itoa(maxCycleLimit)+".", c)
+				return
+			}
+			limit = n
+		}
+
+		// one extra cycle tells whether the result was cut off
+		cycles, err := store.FindCycles(graphId, limit+1)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		rs := CyclesRs{Cycles: cycles}
+		if len(cycles) > limit {
+			rs.Cycles, rs.Truncated = cycles[:limit], true
+		}
+		response.OkWithData(rs, c)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func cyclesRequest(t *testing.T, store model.GraphStore, url string) (*httptest.ResponseRecorder, CyclesRs) {
+	t.Helper()
+	router := gin.Default()
+	router.GET("/graphs/:id/cycles", CyclesHandler(store))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var rs struct {
+		Data CyclesRs `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	}
+	return w, rs.Data
+}
+
+func TestCyclesHandler(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{
+		Identity: "g",
+		Nodes:    []model.Node{{Identity: "a"}, {Identity: "ab"}, {Identity: "b"}},
+		Edges: []model.Edge{
+			{Identity: "e1", FromIdentity: "a", ToIdentity: "ab"},
+			{Identity: "e2", FromIdentity: "ab", ToIdentity: "a"},
+			{Identity: "e3", FromIdentity: "ab", ToIdentity: "b"},
+			{Identity: "e4", FromIdentity: "b", ToIdentity: "a"},
+		},
+	}
+	assert.NoError(t, store.Create(g))
+
+	w, rs := cyclesRequest(t, store, "/graphs/1/cycles")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, [][]string{{"a", "ab"}, {"a", "ab", "b"}}, rs.Cycles)
+	assert.False(t, rs.Truncated)
+
+	w, rs = cyclesRequest(t, store, "/graphs/1/cycles?limit=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, rs.Cycles, 1)
+	assert.True(t, rs.Truncated)
+}
+
+func TestCyclesHandler_SelfLoop(t *testing.T) {
+	store := model.NewMemoryStore()
+	g := &model.Graph{
+		Identity: "g",
+		Nodes:    []model.Node{{Identity: "a"}, {Identity: "b"}},
+		Edges: []model.Edge{
+			{Identity: "e1", FromIdentity: "a", ToIdentity: "a"},
+			{Identity: "e2", FromIdentity: "a", ToIdentity: "b"},
+		},
+	}
+	assert.NoError(t, store.Create(g))
+
+	w, rs := cyclesRequest(t, store, "/graphs/1/cycles")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, [][]string{{"a"}}, rs.Cycles)
+	assert.False(t, rs.Truncated)
+}
+
+func TestCyclesHandler_BadRequests(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+
+	w, _ := cyclesRequest(t, store, "/graphs/1/cycles?limit=0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = cyclesRequest(t, store, "/graphs/1/cycles")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteGraphHandler removes the graph with the :id route parameter together
+// with all its revisions, nodes and edges.
+func DeleteGraphHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		if _, err := store.Delete(graphId); err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		response.Ok(c)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteGraphHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id from graph where identity").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec("delete from edge where graph_id in").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("delete from node where graph_id in").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("delete from graph where id in").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	router := gin.Default()
+	router.DELETE("/graphs/:id", DeleteGraphHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodDelete, "/graphs/2", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteGraphHandler_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id from graph where identity").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	router := gin.Default()
+	router.DELETE("/graphs/:id", DeleteGraphHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodDelete, "/graphs/2", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"container/heap"
+	"context"
+	"slices"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+)
+
+type costItem struct {
+	node string
+	cost float64
+}
+
+// costQueue is a min-heap of nodes ordered by their tentative cost.
+type costQueue []costItem
+
+func (q costQueue) Len() int            { return len(q) }
+func (q costQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q costQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *costQueue) Push(x interface{}) { *q = append(*q, x.(costItem)) }
+func (q *costQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// findCheapestPath runs Dijkstra's algorithm from start and returns the total cost
+// and node list of the cheapest path to end. ok is false when end is unreachable.
+// Edge costs are validated to be non-negative, which Dijkstra relies on.
+// The search gives up with the context error once ctx is done.
+func findCheapestPath(ctx context.Context, start string, end string, graphMap map[string][]model.Arc) (cost float64, path []string, ok bool, err error) {
+	stop := &stopper{ctx: ctx}
+	p, ok := dijkstra(stop, start, end, graphMap, nil)
+	if stop.err != nil {
+		return 0, nil, false, stop.err
+	}
+	return p.cost, p.nodes, ok, nil
+}
+
+// step is how the cheapest known path reaches a node.
+type step struct {
+	from string
+	arc  model.Arc
+}
+
+// dijkstra returns the cheapest path from start to end that does not use an arc
+// for which skip returns true; a nil skip allows every arc. ok is false when no
+// such path exists or stop ended the search.
+func dijkstra(stop *stopper, start string, end string, graphMap map[string][]model.Arc, skip func(from string, a model.Arc) bool) (p foundPath, ok bool) {
+	dist := map[string]float64{start: 0}
+	prev := map[string]step{}
+	done := map[string]bool{}
+
+	q := &costQueue{{node: start, cost: 0}}
+	for q.Len() > 0 {
+		if stop.stopped() {
+			return foundPath{}, false
+		}
+		cur := heap.Pop(q).(costItem)
+		if done[cur.node] {
+			continue
+		}
+		done[cur.node] = true
+		if cur.node == end {
+			break
+		}
+		for _, next := range graphMap[cur.node] {
+			if done[next.To] || (skip != nil && skip(cur.node, next)) {
+				continue
+			}
+			c := cur.cost + next.Cost
+			if d, seen := dist[next.To]; !seen || c < d {
+				dist[next.To] = c
+				prev[next.To] = step{from: cur.node, arc: next}
+				heap.Push(q, costItem{node: next.To, cost: c})
+			}
+		}
+	}
+
+	if !done[end] {
+		return foundPath{}, false
+	}
+	return tracePath(start, end, prev, dist[end]), true
+}
+
+// tracePath follows prev back from end to start and returns the path it took.
+func tracePath(start string, end string, prev map[string]step, cost float64) foundPath {
+	p := foundPath{nodes: []string{end}, cost: cost}
+	for n := end; n != start; n = prev[n].from {
+		p.nodes = append(p.nodes, prev[n].from)
+		p.arcs = append(p.arcs, prev[n].arc)
+	}
+	slices.Reverse(p.nodes)
+	slices.Reverse(p.arcs)
+	return p
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCheapestPath_PrefersCheaperDetour(t *testing.T) {
+	graphMap := map[string][]model.Arc{
+		"a": {{To: "e", Cost: 42}, {To: "b", Cost: 15}},
+		"b": {{To: "e", Cost: 10}},
+	}
+
+	cost, path, ok, err := findCheapestPath(context.Background(), "a", "e", graphMap)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 25.0, cost)
+	assert.Equal(t, []string{"a", "b", "e"}, path)
+}
+
+func TestFindCheapestPath_CostAboveHundred(t *testing.T) {
+	graphMap := map[string][]model.Arc{
+		"a": {{To: "b", Cost: 150}},
+		"b": {{To: "c", Cost: 250.5}},
+	}
+
+	cost, path, ok, err := findCheapestPath(context.Background(), "a", "c", graphMap)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 400.5, cost)
+	assert.Equal(t, []string{"a", "b", "c"}, path)
+}
+
+func TestFindCheapestPath_Unreachable(t *testing.T) {
+	graphMap := map[string][]model.Arc{
+		"a": {{To: "b", Cost: 1}},
+	}
+
+	_, path, ok, err := findCheapestPath(context.Background(), "a", "c", graphMap)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, path)
+}
+
+func TestFindCheapestPath_SameNode(t *testing.T) {
+	cost, path, ok, err := findCheapestPath(context.Background(), "a", "a", map[string][]model.Arc{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, cost)
+	assert.Equal(t, []string{"a"}, path)
+}
+
+func TestFindCheapestPath_LargeGraph(t *testing.T) {
+	// A dense layered graph with 50k edges; exhaustive search would never finish.
+	const layers, width = 50, 32
+	graphMap := map[string][]model.Arc{}
+	name := func(l, i int) string { return strconv.Itoa(l) + "-" + strconv.Itoa(i) }
+	for l := 0; l < layers-1; l++ {
+		for i := 0; i < width; i++ {
+			for j := 0; j < width; j++ {
+				cost := 1.0
+				if i != j {
+					cost = 2
+				}
+				graphMap[name(l, i)] = append(graphMap[name(l, i)], model.Arc{To: name(l+1, j), Cost: cost})
+			}
+		}
+	}
+
+	cost, path, ok, err := findCheapestPath(context.Background(), name(0, 0), name(layers-1, 0), graphMap)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, float64(layers-1), cost)
+	assert.Len(t, path, layers)
+}
+
+func TestFindCheapestPath_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, path, ok, err := findCheapestPath(ctx, "a", "b", map[string][]model.Arc{"a": {{To: "b", Cost: 1}}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ok)
+	assert.Nil(t, path)
+}
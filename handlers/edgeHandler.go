@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+type EdgeRq struct {
+	Id   string   `json:"id"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Cost *float64 `json:"cost"`
+}
+
+type EdgeRs struct {
+	Id   string  `json:"id"`
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Cost float64 `json:"cost"`
+	// GraphId is the id of the revision a change was saved as.
+	GraphId int `json:"graphId,omitempty"`
+}
+
+func toEdgeRs(e model.Edge) EdgeRs {
+	return EdgeRs{Id: e.Identity, From: e.FromIdentity, To: e.ToIdentity, Cost: e.Cost}
+}
+
+// ListEdgesHandler lists the edges of the graph with the :id route parameter.
+func ListEdgesHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		edges, err := store.ListEdges(graphId)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		rs := make([]EdgeRs, len(edges))
+		for i, e := range edges {
+			rs[i] = toEdgeRs(e)
+		}
+		response.OkWithData(rs, c)
+	}
+}
+
+// GetEdgeHandler returns the edge :edgeId of graph :id.
+func GetEdgeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		e, err := store.GetEdge(graphId, c.Param("edgeId"))
+		if err != nil {
+			modelErrorResult(err, "Edge not found.", c)
+			return
+		}
+		response.OkWithData(toEdgeRs(e), c)
+	}
+}
+
+// CreateEdgeHandler adds an edge to graph :id as a new revision. Both end nodes must exist, the
+// cost must be non-negative and only one edge may join the same pair of nodes.
+func CreateEdgeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		rq := EdgeRq{}
+		if err := c.ShouldBindJSON(&rq); err != nil || rq.Id == "" || rq.From == "" || rq.To == "" || rq.Cost == nil {
+			response.ValidationFailureWithMessage("Invalid params.", c)
+			return
+		}
+		e := model.Edge{Identity: rq.Id, FromIdentity: rq.From, ToIdentity: rq.To, Cost: *rq.Cost}
+		revisionId, err := store.CreateEdge(graphId, &e)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		rs := toEdgeRs(e)
+		rs.GraphId = revisionId
+		response.OkWithData(rs, c)
+	}
+}
+
+// UpdateEdgeHandler changes the cost of the edge :edgeId of graph :id in a new
+// revision.
+func UpdateEdgeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		rq := EdgeRq{}
+		if err := c.ShouldBindJSON(&rq); err != nil || rq.Cost == nil {
+			response.ValidationFailureWithMessage("Invalid params.", c)
+			return
+		}
+		if (rq.Id != "" && rq.Id != c.Param("edgeId")) || rq.From != "" || rq.To != "" {
+			response.ValidationFailureWithMessage("Only the cost of an edge can be changed.", c)
+			return
+		}
+		e := model.Edge{Identity: c.Param("edgeId"), Cost: *rq.Cost}
+		revisionId, err := store.UpdateEdge(graphId, &e)
+		if err != nil {
+			modelErrorResult(err, "Edge not found.", c)
+			return
+		}
+		rs := toEdgeRs(e)
+		rs.GraphId = revisionId
+		response.OkWithData(rs, c)
+	}
+}
+
+// DeleteEdgeHandler removes the edge :edgeId of graph :id in a new revision.
+func DeleteEdgeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		revisionId, err := store.DeleteEdge(graphId, c.Param("edgeId"))
+		if err != nil {
+			modelErrorResult(err, "Edge not found.", c)
+			return
+		}
+		response.OkWithData(RevisionRs{GraphId: revisionId}, c)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEdgeHandler_UnknownNode(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "a", 1)
+	expectLiveNode(mock, "z", 0)
+	mock.ExpectRollback()
+
+	router := gin.Default()
+	router.POST("/graphs/:id/edges", CreateEdgeHandler(model.NewPostgresStore(db)))
+
+	cost := 1.0
+	jsonPayload, err := json.Marshal(EdgeRq{Id: "e9", From: "a", To: "z", Cost: &cost})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/edges", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateEdgeHandler_NegativeCost(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	router := gin.Default()
+	router.POST("/graphs/:id/edges", CreateEdgeHandler(model.NewPostgresStore(db)))
+
+	cost := -2.0
+	jsonPayload, err := json.Marshal(EdgeRq{Id: "e9", From: "a", To: "b", Cost: &cost})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/edges", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateEdgeHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	mock.ExpectQuery("select e.id, .* from edge e").
+		WithArgs("g", "e1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "from_id", "from_identity", "to_id", "to_identity", "cost"}).
+			AddRow(1, "e1", 1, "a", 2, "b", 3.0))
+	mock.ExpectExec("update edge set removed_in").
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("insert into edge").
+		WithArgs("e1", 1, "a", 2, "b", 4.0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	router := gin.Default()
+	router.PUT("/graphs/:id/edges/:edgeId", UpdateEdgeHandler(model.NewPostgresStore(db)))
+
+	cost := 4.0
+	jsonPayload, err := json.Marshal(EdgeRq{Cost: &cost})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, "/graphs/1/edges/e1", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rs struct {
+		Data EdgeRs `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Equal(t, EdgeRs{Id: "e1", From: "a", To: "b", Cost: 4, GraphId: 2}, rs.Data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteEdgeHandler_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	mock.ExpectExec("update edge set removed_in").
+		WithArgs(2, "e9", "g").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	router := gin.Default()
+	router.DELETE("/graphs/:id/edges/:edgeId", DeleteEdgeHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodDelete, "/graphs/1/edges/e9", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateEdgeHandler_KeepsRevision(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "a"}, {Identity: "b"}, {Identity: "c"}}}
+	g.Edges = []model.Edge{
+		{Identity: "e1", FromIdentity: "a", ToIdentity: "b", Cost: 1},
+		{Identity: "e2", FromIdentity: "b", ToIdentity: "c", Cost: 1},
+		{Identity: "e3", FromIdentity: "a", ToIdentity: "c", Cost: 5},
+	}
+	assert.NoError(t, store.Create(g))
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(store, 1, testLimits))
+	router.PUT("/graphs/:id/edges/:edgeId", UpdateEdgeHandler(store))
+	cheapest := func(graphId int) CheapestPathRs {
+		body, err := json.Marshal(FindPathRq{Queries: []Query{{Cheapest: CheapestPathRq{Start: "a", End: "c"}}}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/graphs/"+strconv.Itoa(graphId)+"/paths", bytes.NewReader(body))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rs FindPathRs
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+		return *rs.Answers[0].Cheapest
+	}
+	before := cheapest(1)
+	assert.Equal(t, 2.0, *before.Cost)
+
+	cost := 10.0
+	jsonPayload, err := json.Marshal(EdgeRq{Cost: &cost})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, "/graphs/1/edges/e2", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rs struct {
+		Data EdgeRs `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.NotEqual(t, 1, rs.Data.GraphId)
+
+	// revision 1 answers as before, the new revision takes the direct edge
+	assert.Equal(t, before, cheapest(1))
+	assert.Equal(t, 5.0, *cheapest(rs.Data.GraphId).Cost)
+	edge, err := store.GetEdge(1, "e2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, edge.Cost)
+}
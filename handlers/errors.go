@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+// modelErrorResult writes the response for an error returned by a model mutation.
+func modelErrorResult(err error, notFound string, c *gin.Context) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		response.NotFoundWithMessage(notFound, c)
+	case errors.Is(err, model.ErrNegativeCost), errors.Is(err, model.ErrUnknownNode):
+		response.ValidationFailureWithMessage(err.Error(), c)
+	case errors.Is(err, model.ErrDuplicateNode), errors.Is(err, model.ErrDuplicateEdge), errors.Is(err, model.ErrDuplicateEdgeNodes),
+		errors.Is(err, model.ErrRevisionConflict), errors.Is(err, model.ErrNotLatestRevision):
+		response.ConflictWithMessage(err.Error(), c)
+	default:
+		response.InteralErrorWithMessage("Database failure.", c)
+	}
+}
@@ -1,6 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/GuohaoMa/tucowDemo/common/response"
 	"github.com/GuohaoMa/tucowDemo/model"
 	"github.com/gin-gonic/gin"
@@ -9,6 +19,16 @@ import (
 type PathRq struct {
 	Start string `json:"start,omitempty"`
 	End   string `json:"end,omitempty"`
+	// SortBy orders the paths; "cost" puts the cheapest first. By default paths
+	// are returned in the order they are found.
+	SortBy string `json:"sortBy,omitempty"`
+	// MaxPaths is the page size, at most SearchLimits.MaxPaths which is also the default.
+	MaxPaths int `json:"maxPaths,omitempty"`
+	// MaxDepth skips paths with more edges, MaxCost paths that cost more.
+	MaxDepth int      `json:"maxDepth,omitempty"`
+	MaxCost  *float64 `json:"maxCost,omitempty"`
+	// Cursor is the NextCursor of the previous page of the same query.
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type CheapestPathRq struct {
@@ -16,43 +36,196 @@ type CheapestPathRq struct {
 	End   string `json:"end,omitempty"`
 }
 
+// KCheapestRq asks for the K cheapest paths without repeated nodes.
+type KCheapestRq struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// K is at most SearchLimits.MaxPaths.
+	K int `json:"k,omitempty"`
+}
+
+// ShortestRq asks for a path with the fewest edges.
+type ShortestRq struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// TieBreak "cost" picks the cheapest of the paths with the fewest edges. By
+	// default the first one found is returned.
+	TieBreak string `json:"tieBreak,omitempty"`
+}
+
+// NodeSetRq asks for the nodes reachable from Node, or for its ancestors: the
+// nodes Node can be reached from.
+type NodeSetRq struct {
+	Node string `json:"node,omitempty"`
+	// MaxDepth leaves out nodes more edges away.
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// Distances adds the fewest edges between Node and every returned node.
+	Distances bool `json:"distances,omitempty"`
+}
+
 type Query struct {
-	Paths    PathRq         `json:"paths,omitempty"`
-	Cheapest CheapestPathRq `json:"cheapest,omitempty"`
+	Paths     PathRq         `json:"paths,omitempty"`
+	Cheapest  CheapestPathRq `json:"cheapest,omitempty"`
+	KCheapest KCheapestRq    `json:"kcheapest,omitempty"`
+	Shortest  ShortestRq     `json:"shortest,omitempty"`
+	Reachable NodeSetRq      `json:"reachable,omitempty"`
+	Ancestors NodeSetRq      `json:"ancestors,omitempty"`
+}
+
+// empty reports whether the query asks for nothing.
+func (q Query) empty() bool {
+	return q.Paths == (PathRq{}) && q.Cheapest == (CheapestPathRq{}) && q.KCheapest == (KCheapestRq{}) && q.Shortest == (ShortestRq{}) &&
+		q.Reachable == (NodeSetRq{}) && q.Ancestors == (NodeSetRq{})
 }
 
 type FindPathRq struct {
 	Queries []Query `json:"queries,omitempty"`
+	// Strict rejects the whole request when the graph contains any cycle.
+	Strict bool `json:"strict,omitempty"`
 }
 
 type PathRs struct {
 	From     string     `json:"from,omitempty"`
 	To       string     `json:"to,omitempty"`
 	AllPaths [][]string `json:"paths,omitempty"`
+	// Details describes the path with the same index in AllPaths.
+	Details []PathDetail `json:"details,omitempty"`
+	// NextCursor is set when more paths follow this page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Truncated is set when the query ran out of time before the page was full.
+	// A NextCursor then continues the search after the last returned path.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type PathDetail struct {
+	Cost float64 `json:"cost"`
+	// Edges lists the traversed edges in path order.
+	Edges []PathEdge `json:"edges"`
+}
+
+type PathEdge struct {
+	Id   string  `json:"id"`
+	Cost float64 `json:"cost"`
 }
 
 type CheapestPathRs struct {
 	From string      `json:"from,omitempty"`
 	To   string      `json:"to,omitempty"`
 	Path interface{} `json:"paths,omitempty"`
+	Cost *float64    `json:"cost,omitempty"`
+}
+
+// ShortestRs is shaped like CheapestPathRs, with the number of edges of the path
+// in Hops.
+type ShortestRs struct {
+	From string      `json:"from,omitempty"`
+	To   string      `json:"to,omitempty"`
+	Path interface{} `json:"paths,omitempty"`
+	Hops *int        `json:"hops,omitempty"`
+	Cost *float64    `json:"cost,omitempty"`
+}
+
+// NodeSetRs lists nodes nearest first, and by identity at the same distance.
+// Node itself is not listed.
+type NodeSetRs struct {
+	Node      string         `json:"node,omitempty"`
+	Nodes     []string       `json:"nodes,omitempty"`
+	Distances map[string]int `json:"distances,omitempty"`
+}
+
+// Codes of AnswerError.
+const (
+	ErrCodeUnknownNode   = "unknown_node"
+	ErrCodeInvalidQuery  = "invalid_query"
+	ErrCodeLimitExceeded = "limit_exceeded"
+	ErrCodeTimeout       = "timeout"
+)
+
+// AnswerError explains why a single query of a batch could not be answered.
+// The other queries of the batch are answered as usual.
+type AnswerError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type Answer struct {
 	Paths    *PathRs         `json:"paths,omitempty"`
 	Cheapest *CheapestPathRs `json:"cheapest,omitempty"`
+	// KCheapest lists the paths cheapest first.
+	KCheapest *PathRs      `json:"kcheapest,omitempty"`
+	Shortest  *ShortestRs  `json:"shortest,omitempty"`
+	Reachable *NodeSetRs   `json:"reachable,omitempty"`
+	Ancestors *NodeSetRs   `json:"ancestors,omitempty"`
+	Error     *AnswerError `json:"error,omitempty"`
+}
+
+// pathList returns the part of the answer whose paths are found one by one, or nil.
+func (a Answer) pathList() *PathRs {
+	if a.Paths != nil {
+		return a.Paths
+	}
+	return a.KCheapest
 }
+
 type FindPathRs struct {
 	Answers []Answer `json:"answers,omitempty"`
 }
 
-type EdgeCost struct {
-	To   string
-	Cost float64
+// SearchLimits are the server side bounds of path searches.
+type SearchLimits struct {
+	// MaxPaths caps the paths of an answer page and the paths collected to sort
+	// them by cost.
+	MaxPaths int
+	// QueryTimeout bounds the search time of every query; zero means no bound.
+	QueryTimeout time.Duration
+}
+
+// queryContext returns the context a single query of a request searches under.
+func (l SearchLimits) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.QueryTimeout > 0 {
+		return context.WithTimeout(ctx, l.QueryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutError is the error of a query that ran out of time without a result.
+func (l SearchLimits) timeoutError() *AnswerError {
+	return &AnswerError{Code: ErrCodeTimeout, Message: fmt.Sprintf("The query did not finish within %s.", l.QueryTimeout)}
 }
 
-func FindPathHandler(graph *model.Graph) gin.HandlerFunc {
+// FindPathHandler answers path queries. The graph is taken from the :id route
+// parameter when present, otherwise the latest revision of defaultGraphId is used.
+// An optional ?revision= query parameter selects another revision of that graph.
+// Graphs are read from the index cache of store, so repeated queries do not hit
+// the database. Searches stop when the client goes away and every query is
+// bounded by limits.QueryTimeout.
+func FindPathHandler(store *model.CachedStore, defaultGraphId int, limits SearchLimits) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		g := *&model.Graph{Db: graph.Db, Id: graph.Id}
+		var graphId int
+		if c.Param("id") != "" {
+			id, ok := graphIdParam(c)
+			if !ok {
+				return
+			}
+			graphId = id
+		} else {
+			// the default graph follows the changes made after startup
+			id, err := store.LatestRevision(defaultGraphId)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					response.NotFoundWithMessage("Graph not found.", c)
+					return
+				}
+				response.InteralErrorWithMessage("Failed to load graph.", c)
+				return
+			}
+			graphId = id
+		}
+		graphId, ok := resolveRevision(c, store, graphId)
+		if !ok {
+			return
+		}
+
 		findPathRq := FindPathRq{}
 		if err := c.ShouldBindJSON(&findPathRq); err != nil {
 			response.InteralErrorWithMessage("Json binding failure.", c)
@@ -64,68 +237,360 @@ func FindPathHandler(graph *model.Graph) gin.HandlerFunc {
 			return
 		}
 
-		g.Get()
-
-		cy, _ := g.FindCycles()
-		if len(cy) > 0 {
-			response.ValidationFailureWithMessage("Cycle detected.", c)
+		ix, err := store.Index(graphId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.NotFoundWithMessage("Graph not found.", c)
+				return
+			}
+			response.InteralErrorWithMessage("Failed to load graph.", c)
 			return
 		}
+		if err := store.MarkUsed(graphId); err != nil {
+			log.Println("failed to mark graph", graphId, "as used:", err)
+		}
 
-		graphMap := make(map[string][]EdgeCost)
-		for _, edge := range g.Edges {
-			if edge.FromIdentity != edge.ToIdentity {
-				graphMap[edge.FromIdentity] = append(graphMap[edge.FromIdentity], EdgeCost{To: edge.ToIdentity, Cost: edge.Cost})
+		if findPathRq.Strict {
+			if !ix.Acyclic {
+				response.ValidationFailureWithMessage("Cycle detected.", c)
+				return
 			}
 		}
 
+		if acceptsNDJSON(c) {
+			streamAnswers(c, findPathRq.Queries, ix, limits)
+			return
+		}
+
 		findPathRs := FindPathRs{}
-		for _, q := range findPathRq.Queries {
-			if q.Paths != (PathRq{}) {
-				result := [][]string{}
-				start, end := q.Paths.Start, q.Paths.End
-				findAllPaths(start, start, end, []string{start}, &result, graphMap)
-				findPathRs.Answers = append(findPathRs.Answers, Answer{Paths: &PathRs{From: start, To: end, AllPaths: result}})
-			}
-			if q.Cheapest != (CheapestPathRq{}) {
-				result := []string{}
-				start, end := q.Cheapest.Start, q.Cheapest.End
-				_, path := findCheapestPath(start, start, end, 0, 100, []string{start}, result, graphMap)
-				a := Answer{Cheapest: &CheapestPathRs{From: start, To: end, Path: path}}
-				if len(path) == 0 {
-					a.Cheapest.Path = false
+		page := []foundPath{}
+		runQueries(c.Request.Context(), findPathRq.Queries, ix, limits, func(_ int, p foundPath) bool {
+			page = append(page, p)
+			return true
+		}, func(_ int, a Answer) bool {
+			if rs := a.pathList(); rs != nil && a.Error == nil {
+				rs.AllPaths = make([][]string, len(page))
+				rs.Details = make([]PathDetail, len(page))
+				for i, p := range page {
+					rs.AllPaths[i], rs.Details[i] = p.nodes, p.detail()
 				}
-				findPathRs.Answers = append(findPathRs.Answers, a)
 			}
-		}
+			page = page[:0]
+			findPathRs.Answers = append(findPathRs.Answers, a)
+			return true
+		})
 
 		c.IndentedJSON(200, findPathRs)
 		return
 	}
 }
 
-func findAllPaths(cur string, from string, end string, path []string, result *[][]string, graphMap map[string][]EdgeCost) {
-	if cur == end {
-		pathCopy := make([]string, len(path))
-		copy(pathCopy, path)
-		*result = append(*result, pathCopy)
-		return
+// runQueries answers queries in order. A query yields an answer for each of its
+// paths, cheapest, kcheapest, shortest, reachable and ancestors parts, or one
+// error answer when it has none.
+// Every answer is passed to answer together with its index; the paths of a
+// paths or kcheapest answer are passed to path first, as they are found, and are not part of the
+// answer itself. Answering stops as soon as a callback returns false or ctx is
+// done.
+func runQueries(ctx context.Context, queries []Query, ix *model.Index, limits SearchLimits, path func(i int, p foundPath) bool, answer func(i int, a Answer) bool) {
+	i := 0
+	next := func(a Answer) bool {
+		// an answer cut short because the client went away is not worth sending
+		if ctx.Err() != nil {
+			return false
+		}
+		i++
+		return answer(i-1, a)
+	}
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			return
+		}
+		if q.empty() {
+			if !next(Answer{Error: &AnswerError{Code: ErrCodeInvalidQuery, Message: "A query must contain paths, cheapest, kcheapest, shortest, reachable or ancestors."}}) {
+				return
+			}
+		}
+		if q.Paths != (PathRq{}) {
+			stopped := false
+			qctx, cancel := limits.queryContext(ctx)
+			a := answerPaths(qctx, q.Paths, ix, limits, func(p foundPath) bool {
+				stopped = !path(i, p)
+				return !stopped
+			})
+			cancel()
+			if stopped || !next(a) {
+				return
+			}
+		}
+		if q.Cheapest != (CheapestPathRq{}) {
+			qctx, cancel := limits.queryContext(ctx)
+			a := answerCheapest(qctx, q.Cheapest, ix, limits)
+			cancel()
+			if !next(a) {
+				return
+			}
+		}
+		if q.KCheapest != (KCheapestRq{}) {
+			stopped := false
+			qctx, cancel := limits.queryContext(ctx)
+			a := answerKCheapest(qctx, q.KCheapest, ix, limits, func(p foundPath) bool {
+				stopped = !path(i, p)
+				return !stopped
+			})
+			cancel()
+			if stopped || !next(a) {
+				return
+			}
+		}
+		if q.Shortest != (ShortestRq{}) {
+			qctx, cancel := limits.queryContext(ctx)
+			a := answerShortest(qctx, q.Shortest, ix, limits)
+			cancel()
+			if !next(a) {
+				return
+			}
+		}
+		if q.Reachable != (NodeSetRq{}) {
+			qctx, cancel := limits.queryContext(ctx)
+			a := Answer{}
+			a.Reachable, a.Error = answerNodeSet(qctx, q.Reachable, ix, ix.Out, limits)
+			cancel()
+			if !next(a) {
+				return
+			}
+		}
+		if q.Ancestors != (NodeSetRq{}) {
+			qctx, cancel := limits.queryContext(ctx)
+			a := Answer{}
+			a.Ancestors, a.Error = answerNodeSet(qctx, q.Ancestors, ix, ix.In, limits)
+			cancel()
+			if !next(a) {
+				return
+			}
+		}
+	}
+}
+
+// answerCheapest answers a cheapest-path query.
+func answerCheapest(ctx context.Context, q CheapestPathRq, ix *model.Index, limits SearchLimits) Answer {
+	a := Answer{Cheapest: &CheapestPathRs{From: q.Start, To: q.End}}
+	if a.Error = checkEnds(ix, q.Start, q.End); a.Error != nil {
+		return a
+	}
+	cost, path, ok, err := findCheapestPath(ctx, q.Start, q.End, ix.Out)
+	switch {
+	case err != nil:
+		a.Error = limits.timeoutError()
+	case ok:
+		a.Cheapest.Path = path
+		a.Cheapest.Cost = &cost
+	default:
+		a.Cheapest.Path = false
 	}
-	for _, next := range graphMap[cur] {
-		if next.To != from {
-			findAllPaths(next.To, cur, end, append(path, next.To), result, graphMap)
+	return a
+}
+
+// checkEnds returns the error of a query from start to end, or nil when both
+// are nodes of the graph.
+func checkEnds(ix *model.Index, start string, end string) *AnswerError {
+	if start == "" || end == "" {
+		return &AnswerError{Code: ErrCodeInvalidQuery, Message: "Both start and end must be set."}
+	}
+	for _, n := range []string{start, end} {
+		if err := checkNode(ix, n); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// checkNode returns the error of a query about node, or nil when it is a node of
+// the graph.
+func checkNode(ix *model.Index, node string) *AnswerError {
+	if node == "" {
+		return &AnswerError{Code: ErrCodeInvalidQuery, Message: "node must be set."}
+	}
+	if !ix.Nodes[node] {
+		return &AnswerError{Code: ErrCodeUnknownNode, Message: fmt.Sprintf("Node %q is not in the graph.", node)}
+	}
+	return nil
 }
 
-func findCheapestPath(cur string, from string, end string, curCost float64, minCost float64, path []string, result []string, graphMap map[string][]EdgeCost) (float64, []string) {
-	if cur == end && curCost < minCost {
-		return curCost, path
+// checkPathRq returns the error of the parameters of an all-paths query, or nil.
+func checkPathRq(q PathRq, limits SearchLimits) *AnswerError {
+	if q.SortBy != "" && q.SortBy != "cost" {
+		return &AnswerError{Code: ErrCodeInvalidQuery, Message: "sortBy must be cost when set."}
+	}
+	if q.MaxPaths < 0 || q.MaxDepth < 0 || (q.MaxCost != nil && *q.MaxCost < 0) {
+		return &AnswerError{Code: ErrCodeInvalidQuery, Message: "maxPaths, maxDepth and maxCost must not be negative."}
 	}
-	for _, next := range graphMap[cur] {
-		if next.To != from {
-			minCost, result = findCheapestPath(next.To, cur, end, curCost+next.Cost, minCost, append(path, next.To), result, graphMap)
+	if q.MaxPaths > limits.MaxPaths {
+		return &AnswerError{Code: ErrCodeLimitExceeded, Message: fmt.Sprintf("maxPaths may be at most %d.", limits.MaxPaths)}
+	}
+	return nil
+}
+
+// answerPaths answers an all-paths query with one page of paths, which are passed
+// to emit instead of being added to the answer. It stops when emit returns false.
+// When ctx is done before the page is full, the paths found so far make up a
+// truncated page; sorting by cost has no useful partial result and times out.
+func answerPaths(ctx context.Context, q PathRq, ix *model.Index, limits SearchLimits, emit func(p foundPath) bool) Answer {
+	a := Answer{Paths: &PathRs{From: q.Start, To: q.End}}
+	if a.Error = checkEnds(ix, q.Start, q.End); a.Error != nil {
+		return a
+	}
+	if a.Error = checkPathRq(q, limits); a.Error != nil {
+		return a
+	}
+	cursor := newPathCursor(ix, q)
+	if q.Cursor != "" {
+		c, err := decodePathCursor(q.Cursor, cursor)
+		if err != nil {
+			a.Error = &AnswerError{Code: ErrCodeInvalidQuery, Message: err.Error()}
+			return a
+		}
+		cursor = c
+	}
+	pageSize := limits.MaxPaths
+	if q.MaxPaths > 0 {
+		pageSize = q.MaxPaths
+	}
+	bounds := pathBounds{maxDepth: q.MaxDepth, maxCost: q.MaxCost}
+
+	if q.SortBy == "cost" {
+		// sorting needs every path, so their number is bounded by the server cap
+		all := []foundPath{}
+		err := findAllPaths(ctx, q.Start, q.End, ix.Out, bounds, nil, func(p foundPath) bool {
+			all = append(all, p)
+			return len(all) <= limits.MaxPaths
+		})
+		if err != nil {
+			a.Error = limits.timeoutError()
+			return a
+		}
+		if len(all) > limits.MaxPaths {
+			a.Error = &AnswerError{Code: ErrCodeLimitExceeded, Message: fmt.Sprintf("More than %d paths match, narrow the query with maxDepth or maxCost to sort them by cost.", limits.MaxPaths)}
+			return a
+		}
+		sort.SliceStable(all, func(i, j int) bool { return all[i].cost < all[j].cost })
+		offset := min(cursor.Offset, len(all))
+		if offset+pageSize < len(all) {
+			a.Paths.NextCursor = cursor.resumeAt(offset + pageSize)
+		}
+		for _, p := range all[offset:min(offset+pageSize, len(all))] {
+			if !emit(p) {
+				break
+			}
+		}
+		return a
+	}
+
+	count := 0
+	last := cursor.After
+	err := findAllPaths(ctx, q.Start, q.End, ix.Out, bounds, cursor.After, func(p foundPath) bool {
+		if count == pageSize {
+			a.Paths.NextCursor = cursor.resumeAfter(last)
+			return false
+		}
+		count++
+		last = p.position
+		return emit(p)
+	})
+	if err != nil {
+		if count == 0 {
+			a.Error = limits.timeoutError()
+			return a
+		}
+		a.Paths.Truncated = true
+		a.Paths.NextCursor = cursor.resumeAfter(last)
+	}
+	return a
+}
+
+// answerShortest answers a fewest-edges query.
+func answerShortest(ctx context.Context, q ShortestRq, ix *model.Index, limits SearchLimits) Answer {
+	a := Answer{Shortest: &ShortestRs{From: q.Start, To: q.End}}
+	if a.Error = checkEnds(ix, q.Start, q.End); a.Error != nil {
+		return a
+	}
+	if q.TieBreak != "" && q.TieBreak != "cost" {
+		a.Error = &AnswerError{Code: ErrCodeInvalidQuery, Message: "tieBreak must be cost when set."}
+		return a
+	}
+	p, ok, err := findShortestPath(ctx, q.Start, q.End, ix.Out, q.TieBreak == "cost")
+	switch {
+	case err != nil:
+		a.Error = limits.timeoutError()
+	case ok:
+		hops := len(p.arcs)
+		a.Shortest.Path = p.nodes
+		a.Shortest.Hops = &hops
+		a.Shortest.Cost = &p.cost
+	default:
+		a.Shortest.Path = false
+	}
+	return a
+}
+
+// answerNodeSet answers a reachable query over ix.Out or an ancestors query over
+// ix.In.
+func answerNodeSet(ctx context.Context, q NodeSetRq, ix *model.Index, graphMap map[string][]model.Arc, limits SearchLimits) (*NodeSetRs, *AnswerError) {
+	rs := &NodeSetRs{Node: q.Node}
+	if err := checkNode(ix, q.Node); err != nil {
+		return rs, err
+	}
+	if q.MaxDepth < 0 {
+		return rs, &AnswerError{Code: ErrCodeInvalidQuery, Message: "maxDepth must not be negative."}
+	}
+	depth, err := findReachable(ctx, q.Node, graphMap, q.MaxDepth)
+	if err != nil {
+		return rs, limits.timeoutError()
+	}
+
+	rs.Nodes = make([]string, 0, len(depth))
+	for n := range depth {
+		rs.Nodes = append(rs.Nodes, n)
+	}
+	slices.SortFunc(rs.Nodes, func(a string, b string) int {
+		if depth[a] != depth[b] {
+			return depth[a] - depth[b]
+		}
+		return strings.Compare(a, b)
+	})
+	if q.Distances {
+		rs.Distances = depth
+	}
+	return rs, nil
+}
+
+// answerKCheapest answers a k-cheapest query, passing the paths to emit cheapest
+// first. Running out of time truncates the list.
+func answerKCheapest(ctx context.Context, q KCheapestRq, ix *model.Index, limits SearchLimits, emit func(p foundPath) bool) Answer {
+	a := Answer{KCheapest: &PathRs{From: q.Start, To: q.End}}
+	if a.Error = checkEnds(ix, q.Start, q.End); a.Error != nil {
+		return a
+	}
+	if q.K < 1 {
+		a.Error = &AnswerError{Code: ErrCodeInvalidQuery, Message: "k must be at least 1."}
+		return a
+	}
+	if q.K > limits.MaxPaths {
+		a.Error = &AnswerError{Code: ErrCodeLimitExceeded, Message: fmt.Sprintf("k may be at most %d.", limits.MaxPaths)}
+		return a
+	}
+
+	count := 0
+	err := findKCheapestPaths(ctx, q.Start, q.End, ix.Out, q.K, func(p foundPath) bool {
+		count++
+		return emit(p)
+	})
+	if err != nil {
+		if count == 0 {
+			a.Error = limits.timeoutError()
+			return a
 		}
+		a.KCheapest.Truncated = true
 	}
-	return minCost, result
+	return a
 }
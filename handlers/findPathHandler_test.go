@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/GuohaoMa/tucowDemo/model"
@@ -20,13 +23,57 @@ func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+var testLimits = SearchLimits{MaxPaths: 100}
+
+type testEdge struct {
+	From string
+	To   string
+	Cost float64
+}
+
+// expectLatestRevision registers the lookup of the latest revision of the default
+// graph graphId, which is graphId itself.
+func expectLatestRevision(mock sqlmock.Sqlmock, graphId int) {
+	mock.ExpectQuery("select id from graph\\s+where identity = \\(select identity from graph where id = \\$1\\) order by revision desc limit 1").
+		WithArgs(graphId).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(graphId))
+}
+
+// expectGraphLoad registers the queries issued by Graph.Get and Graph.MarkUsed.
+func expectGraphLoad(mock sqlmock.Sqlmock, graphId int, edges []testEdge) {
+	mock.ExpectQuery("select id, identity, name, revision, created_at from graph where id = \\$1").
+		WithArgs(graphId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name", "revision", "created_at"}).AddRow(graphId, "g", "Graph", 1, time.Now()))
+
+	nodeRows := sqlmock.NewRows([]string{"id", "identity", "name"})
+	edgeRows := sqlmock.NewRows([]string{"id", "identity", "from_id", "from_identity", "to_id", "to_identity", "cost"})
+	nodeIds := map[string]int{}
+	for i, e := range edges {
+		for _, n := range []string{e.From, e.To} {
+			if _, ok := nodeIds[n]; !ok {
+				nodeIds[n] = len(nodeIds) + 1
+				nodeRows.AddRow(nodeIds[n], n, n)
+			}
+		}
+		edgeRows.AddRow(i+1, "e"+strconv.Itoa(i+1), nodeIds[e.From], e.From, nodeIds[e.To], e.To, e.Cost)
+	}
+	mock.ExpectQuery("select n.id, n.identity, n.name from node n").
+		WithArgs("g", 1).
+		WillReturnRows(nodeRows)
+	mock.ExpectQuery("select e.id, e.identity, e.from_id, e.from_identity, e.to_id, e.to_identity, e.cost from edge e").
+		WithArgs("g", 1).
+		WillReturnRows(edgeRows)
+	mock.ExpectExec("update graph set last_used_at = current_timestamp where id = \\$1").
+		WithArgs(graphId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func TestFindPathHandler_NoQueries(t *testing.T) {
 	db, _ := setupMockDB(t)
 	defer db.Close()
 
 	router := gin.Default()
-	graph := &model.Graph{Db: db, Id: 2}
-	router.POST("/find-path", FindPathHandler(graph))
+	router.POST("/find-path", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 2, testLimits))
 
 	requestPayload := FindPathRq{}
 	jsonPayload, err := json.Marshal(requestPayload)
@@ -46,20 +93,16 @@ func TestFindPathHandler_CycleDetected(t *testing.T) {
 	db, mock := setupMockDB(t)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT (.+) FROM edges WHERE graph_id = ?").
-		WithArgs(3).
-		WillReturnRows(sqlmock.NewRows([]string{"from_identity", "to_identity", "cost"}).
-			AddRow("A", "B", 1).
-			AddRow("B", "C", 1).
-			AddRow("C", "A", 1))
+	expectLatestRevision(mock, 3)
+	expectGraphLoad(mock, 3, []testEdge{{"A", "B", 1}, {"B", "C", 1}, {"C", "A", 1}})
 	router := gin.Default()
-	graph := &model.Graph{Db: db, Id: 3}
-	router.POST("/find-path", FindPathHandler(graph))
+	router.POST("/find-path", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 3, testLimits))
 
 	requestPayload := FindPathRq{
 		Queries: []Query{
 			{Paths: PathRq{Start: "A", End: "B"}},
 		},
+		Strict: true,
 	}
 	jsonPayload, err := json.Marshal(requestPayload)
 	assert.NoError(t, err)
@@ -72,20 +115,18 @@ func TestFindPathHandler_CycleDetected(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestFindPathHandler_NoPathFound(t *testing.T) {
 	db, mock := setupMockDB(t)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT (.+) FROM edges WHERE graph_id = ?").
-		WithArgs(4).
-		WillReturnRows(sqlmock.NewRows([]string{"from_identity", "to_identity", "cost"}).
-			AddRow("A", "B", 1))
+	expectLatestRevision(mock, 4)
+	expectGraphLoad(mock, 4, []testEdge{{"A", "B", 1}, {"C", "B", 1}})
 
 	router := gin.Default()
-	graph := &model.Graph{Db: db, Id: 4}
-	router.POST("/find-path", FindPathHandler(graph))
+	router.POST("/find-path", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 4, testLimits))
 
 	requestPayload := FindPathRq{
 		Queries: []Query{
@@ -111,20 +152,18 @@ func TestFindPathHandler_NoPathFound(t *testing.T) {
 	assert.Len(t, response.Answers, 1)
 	assert.NotNil(t, response.Answers[0].Paths)
 	assert.Empty(t, response.Answers[0].Paths.AllPaths)
+	assert.Nil(t, response.Answers[0].Error)
 }
 
 func TestFindPathHandler_NoCheapestPathFound(t *testing.T) {
 	db, mock := setupMockDB(t)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT (.+) FROM edges WHERE graph_id = ?").
-		WithArgs(5).
-		WillReturnRows(sqlmock.NewRows([]string{"from_identity", "to_identity", "cost"}).
-			AddRow("A", "B", 1))
+	expectLatestRevision(mock, 5)
+	expectGraphLoad(mock, 5, []testEdge{{"A", "B", 1}, {"C", "B", 1}})
 
 	router := gin.Default()
-	graph := &model.Graph{Db: db, Id: 5}
-	router.POST("/find-path", FindPathHandler(graph))
+	router.POST("/find-path", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 5, testLimits))
 
 	requestPayload := FindPathRq{
 		Queries: []Query{
@@ -155,3 +194,460 @@ func TestFindPathHandler_NoCheapestPathFound(t *testing.T) {
 		t.Errorf("Expected boolean type for Cheapest.Path, got %T", response.Answers[0].Cheapest.Path)
 	}
 }
+
+func TestFindPathHandler_GraphFromRoute(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	expectGraphLoad(mock, 9, []testEdge{{"A", "B", 1}, {"B", "C", 2}})
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	requestPayload := FindPathRq{
+		Queries: []Query{
+			{Paths: PathRq{Start: "A", End: "C"}},
+		},
+	}
+	jsonPayload, err := json.Marshal(requestPayload)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs/9/paths", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response FindPathRs
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Answers, 1)
+	assert.Equal(t, [][]string{{"A", "B", "C"}}, response.Answers[0].Paths.AllPaths)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_DefaultGraphFollowsChanges(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	assert.NoError(t, store.Create(&model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "A"}, {Identity: "B"}}}))
+
+	router := gin.Default()
+	router.POST("/graphs/paths", FindPathHandler(store, 1, testLimits))
+	paths := func() [][]string {
+		body := `{"queries": [{"paths": {"start": "A", "end": "B"}}]}`
+		req, err := http.NewRequest(http.MethodPost, "/graphs/paths", strings.NewReader(body))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rs FindPathRs
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+		return rs.Answers[0].Paths.AllPaths
+	}
+	assert.Empty(t, paths())
+
+	_, err := store.CreateEdge(1, &model.Edge{Identity: "e1", FromIdentity: "A", ToIdentity: "B", Cost: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"A", "B"}}, paths())
+}
+
+func TestFindPathHandler_UnknownGraph(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select id, identity, name, revision, created_at from graph where id = \\$1").
+		WithArgs(42).
+		WillReturnError(sql.ErrNoRows)
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	requestPayload := FindPathRq{
+		Queries: []Query{
+			{Paths: PathRq{Start: "A", End: "C"}},
+		},
+	}
+	jsonPayload, err := json.Marshal(requestPayload)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs/42/paths", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFindPathHandler_CyclicGraph(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	expectLatestRevision(mock, 6)
+	expectGraphLoad(mock, 6, []testEdge{{"A", "B", 1}, {"B", "C", 1}, {"C", "A", 1}, {"B", "D", 5}, {"C", "D", 1}})
+
+	router := gin.Default()
+	router.POST("/find-path", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 6, testLimits))
+
+	requestPayload := FindPathRq{
+		Queries: []Query{
+			{Paths: PathRq{Start: "A", End: "D"}},
+			{Cheapest: CheapestPathRq{Start: "A", End: "D"}},
+		},
+	}
+	jsonPayload, err := json.Marshal(requestPayload)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/find-path", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response FindPathRs
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Answers, 2)
+	assert.ElementsMatch(t, [][]string{{"A", "B", "D"}, {"A", "B", "C", "D"}}, response.Answers[0].Paths.AllPaths)
+	assert.Equal(t, []interface{}{"A", "B", "C", "D"}, response.Answers[1].Cheapest.Path)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_Revision(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select id from graph").
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectGraphLoad(mock, 3, []testEdge{{"A", "C", 1}})
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	requestPayload := FindPathRq{
+		Queries: []Query{
+			{Paths: PathRq{Start: "A", End: "C"}},
+		},
+	}
+	jsonPayload, err := json.Marshal(requestPayload)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs/9/paths?revision=1", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response FindPathRs
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"A", "C"}}, response.Answers[0].Paths.AllPaths)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_CachesGraph(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// the graph is loaded and marked as used once, the second query is served from the cache
+	expectGraphLoad(mock, 1, []testEdge{{"A", "B", 1}})
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	for i := 0; i < 2; i++ {
+		body := `{"queries": [{"cheapest": {"start": "A", "end": "B"}}]}`
+		req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_QueryErrors(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	expectGraphLoad(mock, 1, []testEdge{{"A", "B", 1}})
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	body := `{"queries": [
+		{"paths": {"start": "A", "end": "X"}},
+		{"cheapest": {"start": "A"}},
+		{},
+		{"cheapest": {"start": "A", "end": "B"}}
+	]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Len(t, rs.Answers, 4)
+	assert.Equal(t, ErrCodeUnknownNode, rs.Answers[0].Error.Code)
+	assert.Equal(t, "X", rs.Answers[0].Paths.To)
+	assert.Equal(t, ErrCodeInvalidQuery, rs.Answers[1].Error.Code)
+	assert.NotNil(t, rs.Answers[1].Cheapest)
+	assert.Equal(t, ErrCodeInvalidQuery, rs.Answers[2].Error.Code)
+	assert.Nil(t, rs.Answers[3].Error)
+	assert.Equal(t, []interface{}{"A", "B"}, rs.Answers[3].Cheapest.Path)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_PathCosts(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	expectGraphLoad(mock, 1, []testEdge{{"A", "B", 1}, {"B", "D", 5}, {"A", "C", 1}, {"C", "D", 2}})
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+
+	body := `{"queries": [{"paths": {"start": "A", "end": "D", "sortBy": "cost"}}, {"paths": {"start": "A", "end": "D", "sortBy": "hops"}}]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, [][]string{{"A", "C", "D"}, {"A", "B", "D"}}, rs.Answers[0].Paths.AllPaths)
+	assert.Equal(t, []PathDetail{
+		{Cost: 3, Edges: []PathEdge{{Id: "e3", Cost: 1}, {Id: "e4", Cost: 2}}},
+		{Cost: 6, Edges: []PathEdge{{Id: "e1", Cost: 1}, {Id: "e2", Cost: 5}}},
+	}, rs.Answers[0].Paths.Details)
+	assert.Equal(t, ErrCodeInvalidQuery, rs.Answers[1].Error.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPathHandler_Pagination(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "A"}, {Identity: "B"}, {Identity: "C"}, {Identity: "D"}}}
+	for i, e := range [][2]string{{"A", "B"}, {"A", "C"}, {"A", "D"}, {"B", "C"}, {"B", "D"}, {"C", "D"}} {
+		g.Edges = append(g.Edges, model.Edge{Identity: "e" + strconv.Itoa(i), FromIdentity: e[0], ToIdentity: e[1], Cost: float64(i)})
+	}
+	assert.NoError(t, store.Create(g))
+
+	limits := SearchLimits{MaxPaths: 4}
+	query := func(q PathRq) Answer {
+		router := gin.Default()
+		router.POST("/graphs/:id/paths", FindPathHandler(store, 1, limits))
+		body, err := json.Marshal(FindPathRq{Queries: []Query{{Paths: q}}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", bytes.NewReader(body))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rs FindPathRs
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+		return rs.Answers[0]
+	}
+
+	// A to D has four paths, fetched two at a time
+	for sortBy, expected := range map[string][][]string{
+		"":     {{"A", "B", "C", "D"}, {"A", "B", "D"}, {"A", "C", "D"}, {"A", "D"}},
+		"cost": {{"A", "D"}, {"A", "B", "D"}, {"A", "C", "D"}, {"A", "B", "C", "D"}},
+	} {
+		var paths [][]string
+		q := PathRq{Start: "A", End: "D", MaxPaths: 2, SortBy: sortBy}
+		for {
+			a := query(q)
+			assert.Nil(t, a.Error)
+			paths = append(paths, a.Paths.AllPaths...)
+			if a.Paths.NextCursor == "" {
+				break
+			}
+			q.Cursor = a.Paths.NextCursor
+		}
+		assert.Equal(t, expected, paths)
+	}
+
+	assert.Equal(t, ErrCodeLimitExceeded, query(PathRq{Start: "A", End: "D", MaxPaths: 5}).Error.Code)
+	assert.Equal(t, ErrCodeInvalidQuery, query(PathRq{Start: "A", End: "D", Cursor: "bogus"}).Error.Code)
+
+	// a cursor only continues the query and graph version it was issued for
+	cursor := query(PathRq{Start: "A", End: "D", MaxPaths: 1}).Paths.NextCursor
+	assert.NotEmpty(t, cursor)
+	assert.Nil(t, query(PathRq{Start: "A", End: "D", MaxPaths: 2, Cursor: cursor}).Error)
+	assert.Equal(t, ErrCodeInvalidQuery, query(PathRq{Start: "A", End: "C", MaxPaths: 1, Cursor: cursor}).Error.Code)
+	assert.Equal(t, ErrCodeInvalidQuery, query(PathRq{Start: "A", End: "D", MaxDepth: 2, Cursor: cursor}).Error.Code)
+	assert.Equal(t, ErrCodeInvalidQuery, query(PathRq{Start: "A", End: "D", MaxDepth: -1}).Error.Code)
+
+	// sorting collects at most limits.MaxPaths paths
+	limits.MaxPaths = 3
+	assert.Equal(t, ErrCodeLimitExceeded, query(PathRq{Start: "A", End: "D", SortBy: "cost"}).Error.Code)
+	assert.Nil(t, query(PathRq{Start: "A", End: "D", SortBy: "cost", MaxDepth: 2}).Error)
+}
+
+func TestFindPathHandler_CursorAfterEdgeUpdate(t *testing.T) {
+	store := streamTestStore(t)
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(store, 1, testLimits))
+	query := func(graphId int, q PathRq) Answer {
+		body, err := json.Marshal(FindPathRq{Queries: []Query{{Paths: q}}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/graphs/"+strconv.Itoa(graphId)+"/paths", bytes.NewReader(body))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rs FindPathRs
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+		return rs.Answers[0]
+	}
+
+	cursor := query(1, PathRq{Start: "A", End: "C", MaxPaths: 1}).Paths.NextCursor
+	assert.NotEmpty(t, cursor)
+	revisionId, err := store.UpdateEdge(1, &model.Edge{Identity: "e1", Cost: 7})
+	assert.NoError(t, err)
+
+	a := query(revisionId, PathRq{Start: "A", End: "C", MaxPaths: 1, Cursor: cursor})
+	assert.Equal(t, ErrCodeInvalidQuery, a.Error.Code)
+	// the revision the cursor was issued for still continues
+	a = query(1, PathRq{Start: "A", End: "C", MaxPaths: 1, Cursor: cursor})
+	assert.Nil(t, a.Error)
+	assert.Equal(t, [][]string{{"A", "C"}}, a.Paths.AllPaths)
+}
+
+func TestFindPathHandler_QueryTimeout(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{Identity: "g"}
+	nodes := map[string]bool{}
+	for from, arcs := range layeredGraph(10, 20) {
+		for _, a := range arcs {
+			for _, n := range []string{from, a.To} {
+				if !nodes[n] {
+					nodes[n] = true
+					g.Nodes = append(g.Nodes, model.Node{Identity: n})
+				}
+			}
+			g.Edges = append(g.Edges, model.Edge{Identity: a.Edge, FromIdentity: from, ToIdentity: a.To, Cost: a.Cost})
+		}
+	}
+	assert.NoError(t, store.Create(g))
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(store, 1, SearchLimits{MaxPaths: 1000000, QueryTimeout: 20 * time.Millisecond}))
+	body := `{"queries": [{"paths": {"start": "0-0", "end": "9-0"}}, {"paths": {"start": "0-0", "end": "9-0", "sortBy": "cost"}}, {"cheapest": {"start": "0-0", "end": "9-0"}}]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Len(t, rs.Answers, 3)
+	assert.Nil(t, rs.Answers[0].Error)
+	assert.True(t, rs.Answers[0].Paths.Truncated)
+	assert.NotEmpty(t, rs.Answers[0].Paths.AllPaths)
+	assert.NotEmpty(t, rs.Answers[0].Paths.NextCursor)
+	assert.Equal(t, ErrCodeTimeout, rs.Answers[1].Error.Code)
+	assert.Nil(t, rs.Answers[2].Error)
+	assert.Equal(t, 9.0, *rs.Answers[2].Cheapest.Cost)
+}
+
+func TestFindPathHandler_KCheapest(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "A"}, {Identity: "B"}, {Identity: "C"}, {Identity: "D"}}}
+	for i, e := range [][2]string{{"A", "B"}, {"A", "C"}, {"A", "D"}, {"B", "C"}, {"B", "D"}, {"C", "D"}} {
+		g.Edges = append(g.Edges, model.Edge{Identity: "e" + strconv.Itoa(i), FromIdentity: e[0], ToIdentity: e[1], Cost: float64(i)})
+	}
+	assert.NoError(t, store.Create(g))
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(store, 1, SearchLimits{MaxPaths: 3}))
+	body := `{"queries": [{"kcheapest": {"start": "A", "end": "D", "k": 3}}, {"kcheapest": {"start": "A", "end": "D"}}, {"kcheapest": {"start": "A", "end": "D", "k": 4}}]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Len(t, rs.Answers, 3)
+	assert.Nil(t, rs.Answers[0].Error)
+	assert.Equal(t, [][]string{{"A", "D"}, {"A", "B", "D"}, {"A", "C", "D"}}, rs.Answers[0].KCheapest.AllPaths)
+	var costs []float64
+	for _, d := range rs.Answers[0].KCheapest.Details {
+		costs = append(costs, d.Cost)
+	}
+	assert.Equal(t, []float64{2, 4, 6}, costs)
+	assert.Equal(t, ErrCodeInvalidQuery, rs.Answers[1].Error.Code)
+	assert.Equal(t, ErrCodeLimitExceeded, rs.Answers[2].Error.Code)
+}
+
+func TestFindPathHandler_Shortest(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	expectGraphLoad(mock, 1, []testEdge{{"A", "D", 9}, {"A", "B", 1}, {"B", "C", 1}, {"C", "D", 1}, {"E", "A", 1}})
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+	body := `{"queries": [{"shortest": {"start": "A", "end": "D", "tieBreak": "cost"}}, {"shortest": {"start": "D", "end": "E"}}, {"shortest": {"start": "A", "end": "D", "tieBreak": "hops"}}]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Len(t, rs.Answers, 3)
+	assert.Equal(t, []interface{}{"A", "D"}, rs.Answers[0].Shortest.Path)
+	assert.Equal(t, 1, *rs.Answers[0].Shortest.Hops)
+	assert.Equal(t, 9.0, *rs.Answers[0].Shortest.Cost)
+	assert.Equal(t, false, rs.Answers[1].Shortest.Path)
+	assert.Nil(t, rs.Answers[1].Shortest.Hops)
+	assert.Equal(t, ErrCodeInvalidQuery, rs.Answers[2].Error.Code)
+}
+
+func TestFindPathHandler_ReachableAndAncestors(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	expectGraphLoad(mock, 1, []testEdge{{"a", "b", 1}, {"a", "c", 1}, {"b", "d", 1}, {"c", "d", 1}, {"d", "e", 1}})
+
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(model.NewCachedStore(model.NewPostgresStore(db), 10), 1, testLimits))
+	body := `{"queries": [
+		{"reachable": {"node": "a", "maxDepth": 2, "distances": true}},
+		{"ancestors": {"node": "e"}},
+		{"reachable": {"node": "e"}},
+		{"ancestors": {"node": "x"}}
+	]}`
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/paths", strings.NewReader(body))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs FindPathRs
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Len(t, rs.Answers, 4)
+	assert.Equal(t, []string{"b", "c", "d"}, rs.Answers[0].Reachable.Nodes)
+	assert.Equal(t, map[string]int{"b": 1, "c": 1, "d": 2}, rs.Answers[0].Reachable.Distances)
+	assert.Equal(t, []string{"d", "b", "c", "a"}, rs.Answers[1].Ancestors.Nodes)
+	assert.Nil(t, rs.Answers[1].Ancestors.Distances)
+	assert.Nil(t, rs.Answers[2].Error)
+	assert.Empty(t, rs.Answers[2].Reachable.Nodes)
+	assert.Equal(t, ErrCodeUnknownNode, rs.Answers[3].Error.Code)
+}
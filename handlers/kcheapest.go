@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+)
+
+// findKCheapestPaths passes the k cheapest simple paths from start to end to
+// visit in ascending order of cost, until visit returns false. It uses Yen's
+// algorithm: every next path leaves the previous one at some spur node and
+// takes the cheapest detour from there that no shorter path already took.
+// Once ctx is done the search stops and returns the context error.
+func findKCheapestPaths(ctx context.Context, start string, end string, graphMap map[string][]model.Arc, k int, visit func(p foundPath) bool) error {
+	stop := &stopper{ctx: ctx}
+	first, ok := dijkstra(stop, start, end, graphMap, nil)
+	if !ok || !visit(first) {
+		return stop.err
+	}
+
+	found := []foundPath{first}
+	candidates := []foundPath{}
+	seen := map[string]bool{pathKey(first.nodes): true}
+	for len(found) < k {
+		last := found[len(found)-1]
+		rootCost := 0.0
+		for i := 0; i < len(last.arcs); i++ {
+			spur, root := last.nodes[i], last.nodes[:i+1]
+			// the detour must not revisit the root nor leave the spur node the way
+			// an already found path with the same root does
+			blocked := map[string]bool{}
+			for _, n := range root[:i] {
+				blocked[n] = true
+			}
+			taken := map[model.Arc]bool{}
+			for _, p := range found {
+				if len(p.arcs) > i && slices.Equal(p.nodes[:i+1], root) {
+					taken[p.arcs[i]] = true
+				}
+			}
+			detour, ok := dijkstra(stop, spur, end, graphMap, func(from string, a model.Arc) bool {
+				return blocked[a.To] || (from == spur && taken[a])
+			})
+			if stop.err != nil {
+				return stop.err
+			}
+			if ok {
+				p := foundPath{
+					nodes: append(slices.Clone(root), detour.nodes[1:]...),
+					arcs:  append(slices.Clone(last.arcs[:i]), detour.arcs...),
+					cost:  rootCost + detour.cost,
+				}
+				if key := pathKey(p.nodes); !seen[key] {
+					seen[key] = true
+					candidates = append(candidates, p)
+				}
+			}
+			rootCost += last.arcs[i].Cost
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		best := 0
+		for i, p := range candidates {
+			if cheaperPath(p, candidates[best]) {
+				best = i
+			}
+		}
+		next := candidates[best]
+		candidates = slices.Delete(candidates, best, best+1)
+		found = append(found, next)
+		if !visit(next) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pathKey identifies a path by its nodes.
+func pathKey(nodes []string) string {
+	return strings.Join(nodes, "\x00")
+}
+
+// cheaperPath orders paths by cost, then by length and then by their nodes, so
+// equally cheap paths come in a fixed order.
+func cheaperPath(a foundPath, b foundPath) bool {
+	if a.cost != b.cost {
+		return a.cost < b.cost
+	}
+	if len(a.nodes) != len(b.nodes) {
+		return len(a.nodes) < len(b.nodes)
+	}
+	return slices.Compare(a.nodes, b.nodes) < 0
+}
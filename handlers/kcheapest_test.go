@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func kCheapest(t *testing.T, graphMap map[string][]model.Arc, start string, end string, k int) []foundPath {
+	result := []foundPath{}
+	err := findKCheapestPaths(context.Background(), start, end, graphMap, k, func(p foundPath) bool {
+		result = append(result, p)
+		return true
+	})
+	assert.NoError(t, err)
+	return result
+}
+
+func TestFindKCheapestPaths(t *testing.T) {
+	// the classic example of Yen's paper
+	graphMap := map[string][]model.Arc{}
+	for _, e := range []struct {
+		from, to string
+		cost     float64
+	}{
+		{"C", "D", 3}, {"C", "E", 2}, {"D", "F", 4}, {"E", "D", 1},
+		{"E", "F", 2}, {"E", "G", 3}, {"F", "G", 2}, {"F", "H", 1}, {"G", "H", 2},
+	} {
+		graphMap[e.from] = append(graphMap[e.from], model.Arc{Edge: e.from + e.to, To: e.to, Cost: e.cost})
+	}
+
+	paths := kCheapest(t, graphMap, "C", "H", 10)
+	var nodes [][]string
+	var costs []float64
+	for _, p := range paths {
+		nodes = append(nodes, p.nodes)
+		costs = append(costs, p.cost)
+	}
+	assert.Equal(t, [][]string{
+		{"C", "E", "F", "H"},
+		{"C", "E", "G", "H"},
+		{"C", "D", "F", "H"},
+		{"C", "E", "D", "F", "H"},
+		{"C", "E", "F", "G", "H"},
+		{"C", "D", "F", "G", "H"},
+		{"C", "E", "D", "F", "G", "H"},
+	}, nodes)
+	assert.Equal(t, []float64{5, 7, 8, 8, 8, 11, 11}, costs)
+	assert.Equal(t, []string{"CE", "EF", "FH"}, []string{paths[0].arcs[0].Edge, paths[0].arcs[1].Edge, paths[0].arcs[2].Edge})
+
+	assert.Len(t, kCheapest(t, graphMap, "C", "H", 2), 2)
+	assert.Empty(t, kCheapest(t, graphMap, "H", "C", 2))
+}
+
+func TestFindKCheapestPaths_Cycle(t *testing.T) {
+	graphMap := map[string][]model.Arc{
+		"a": {{Edge: "ab", To: "b", Cost: 1}},
+		"b": {{Edge: "ba", To: "a", Cost: 1}, {Edge: "bc", To: "c", Cost: 1}},
+	}
+	paths := kCheapest(t, graphMap, "a", "c", 5)
+	assert.Len(t, paths, 1)
+	assert.Equal(t, []string{"a", "b", "c"}, paths[0].nodes)
+}
+
+func TestFindKCheapestPaths_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := findKCheapestPaths(ctx, "0-0", "4-0", layeredGraph(5, 3), 3, func(p foundPath) bool { return true })
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ListGraphsHandler lists every stored graph revision ordered by identity and revision.
+func ListGraphsHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphs, err := store.List()
+		if err != nil {
+			response.InteralErrorWithMessage("Failed to list graphs.", c)
+			return
+		}
+		response.OkWithData(graphs, c)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListGraphsHandler(t *testing.T) {
+	store := model.NewMemoryStore()
+	for _, identity := range []string{"g2", "g1"} {
+		_, err := store.Import(&model.Graph{Identity: identity, Name: identity, Nodes: []model.Node{{Identity: "a"}}})
+		assert.NoError(t, err)
+	}
+
+	router := gin.Default()
+	router.GET("/graphs", ListGraphsHandler(store))
+
+	req, err := http.NewRequest(http.MethodGet, "/graphs", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs struct {
+		Data []model.Revision `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Len(t, rs.Data, 2)
+	assert.Equal(t, "g1", rs.Data[0].Identity)
+	assert.Equal(t, "g2", rs.Data[1].Identity)
+}
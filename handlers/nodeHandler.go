@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+type NodeRq struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type NodeRs struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// GraphId is the id of the revision a change was saved as.
+	GraphId int `json:"graphId,omitempty"`
+}
+
+// RevisionRs answers a deletion with the id of the revision it was saved as.
+type RevisionRs struct {
+	GraphId int `json:"graphId"`
+}
+
+func toNodeRs(n model.Node) NodeRs {
+	return NodeRs{Id: n.Identity, Name: n.Name}
+}
+
+// ListNodesHandler lists the nodes of the graph with the :id route parameter.
+func ListNodesHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		nodes, err := store.ListNodes(graphId)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		rs := make([]NodeRs, len(nodes))
+		for i, n := range nodes {
+			rs[i] = toNodeRs(n)
+		}
+		response.OkWithData(rs, c)
+	}
+}
+
+// GetNodeHandler returns the node :nodeId of graph :id.
+func GetNodeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		n, err := store.GetNode(graphId, c.Param("nodeId"))
+		if err != nil {
+			modelErrorResult(err, "Node not found.", c)
+			return
+		}
+		response.OkWithData(toNodeRs(n), c)
+	}
+}
+
+// CreateNodeHandler adds a node to graph :id as a new revision. Node ids must be
+// unique in the graph.
+func CreateNodeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		rq := NodeRq{}
+		if err := c.ShouldBindJSON(&rq); err != nil || rq.Id == "" {
+			response.ValidationFailureWithMessage("Invalid params.", c)
+			return
+		}
+		n := model.Node{Identity: rq.Id, Name: rq.Name}
+		revisionId, err := store.CreateNode(graphId, &n)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		rs := toNodeRs(n)
+		rs.GraphId = revisionId
+		response.OkWithData(rs, c)
+	}
+}
+
+// UpdateNodeHandler renames the node :nodeId of graph :id in a new revision.
+func UpdateNodeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		rq := NodeRq{}
+		if err := c.ShouldBindJSON(&rq); err != nil {
+			response.ValidationFailureWithMessage("Invalid params.", c)
+			return
+		}
+		if rq.Id != "" && rq.Id != c.Param("nodeId") {
+			response.ValidationFailureWithMessage("Node id cannot be changed.", c)
+			return
+		}
+		n := model.Node{Identity: c.Param("nodeId"), Name: rq.Name}
+		revisionId, err := store.UpdateNode(graphId, &n)
+		if err != nil {
+			modelErrorResult(err, "Node not found.", c)
+			return
+		}
+		rs := toNodeRs(n)
+		rs.GraphId = revisionId
+		response.OkWithData(rs, c)
+	}
+}
+
+// DeleteNodeHandler removes the node :nodeId of graph :id and the edges using it
+// in a new revision.
+func DeleteNodeHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		revisionId, err := store.DeleteNode(graphId, c.Param("nodeId"))
+		if err != nil {
+			modelErrorResult(err, "Node not found.", c)
+			return
+		}
+		response.OkWithData(RevisionRs{GraphId: revisionId}, c)
+	}
+}
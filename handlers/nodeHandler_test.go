@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectNext registers the statements saving revision 2 with id to after
+// revision 1 of graph g, whose id is from.
+func expectNext(mock sqlmock.Sqlmock, from int, to int) {
+	mock.ExpectQuery("select identity, revision, .* from graph g where id = \\$1").
+		WithArgs(from).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision", "max"}).AddRow("g", 1, 1))
+	mock.ExpectQuery("insert into graph \\(identity, name, revision\\)").
+		WithArgs(from).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision"}).AddRow(to, 2))
+}
+
+// expectLiveNode registers the lookup of node identity in the latest revision of
+// graph g, returning id or no row when id is 0.
+func expectLiveNode(mock sqlmock.Sqlmock, identity string, id int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	if id != 0 {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("select n.id from node n").
+		WithArgs("g", identity).
+		WillReturnRows(rows)
+}
+
+func TestCreateNodeHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "c", 0)
+	mock.ExpectQuery("insert into node").
+		WithArgs("c", "C", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	router := gin.Default()
+	router.POST("/graphs/:id/nodes", CreateNodeHandler(model.NewPostgresStore(db)))
+
+	jsonPayload, err := json.Marshal(NodeRq{Id: "c", Name: "C"})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/nodes", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rs struct {
+		Data NodeRs `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Equal(t, NodeRs{Id: "c", Name: "C", GraphId: 2}, rs.Data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateNodeHandler_Duplicate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "a", 1)
+	mock.ExpectRollback()
+
+	router := gin.Default()
+	router.POST("/graphs/:id/nodes", CreateNodeHandler(model.NewPostgresStore(db)))
+
+	jsonPayload, err := json.Marshal(NodeRq{Id: "a", Name: "A"})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "/graphs/1/nodes", bytes.NewBuffer(jsonPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNodeHandler_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select identity, revision from graph where id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision"}).AddRow("g", 1))
+	mock.ExpectQuery("select n.id, n.identity, n.name from node n .* and n.identity = \\$3").
+		WithArgs("g", 1, "z").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name"}))
+
+	router := gin.Default()
+	router.GET("/graphs/:id/nodes/:nodeId", GetNodeHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodGet, "/graphs/1/nodes/z", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateNodeHandler_OlderRevision(t *testing.T) {
+	store := model.NewMemoryStore()
+	assert.NoError(t, store.Create(&model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "a"}}}))
+
+	router := gin.Default()
+	router.POST("/graphs/:id/nodes", CreateNodeHandler(store))
+	post := func(id string) *httptest.ResponseRecorder {
+		jsonPayload, err := json.Marshal(NodeRq{Id: id, Name: id})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/graphs/1/nodes", bytes.NewBuffer(jsonPayload))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// the second change is sent to revision 1 again and would drop node b
+	assert.Equal(t, http.StatusOK, post("b").Code)
+	w := post("c")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), model.ErrNotLatestRevision.Error())
+	nodes, err := store.ListNodes(2)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+}
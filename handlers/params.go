@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+// graphIdParam parses the :id route parameter. On failure the error response
+// has already been written and ok is false.
+func graphIdParam(c *gin.Context) (id int, ok bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ValidationFailureWithMessage("Invalid graph id.", c)
+		return 0, false
+	}
+	return id, true
+}
+
+// resolveRevision returns the id of the revision of graph id given by the
+// ?revision= query parameter, or id itself when there is none. On failure the
+// error response has already been written and ok is false.
+func resolveRevision(c *gin.Context, store model.GraphStore, id int) (resolved int, ok bool) {
+	rev := c.Query("revision")
+	if rev == "" {
+		return id, true
+	}
+	revision, err := strconv.Atoi(rev)
+	if err != nil {
+		response.ValidationFailureWithMessage("Invalid revision.", c)
+		return 0, false
+	}
+	resolved, err = store.ResolveRevision(id, revision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.NotFoundWithMessage("Graph revision not found.", c)
+			return 0, false
+		}
+		response.InteralErrorWithMessage("Failed to load graph revision.", c)
+		return 0, false
+	}
+	return resolved, true
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+)
+
+// findReachable returns every node that can be reached from start along the
+// arcs of graphMap, mapped to the fewest arcs needed to get there. start itself
+// is left out. With a positive maxDepth nodes further away are left out too.
+// Walking the incoming arcs of model.Index.In finds the ancestors instead.
+// Once ctx is done the search stops and returns the context error.
+func findReachable(ctx context.Context, start string, graphMap map[string][]model.Arc, maxDepth int) (map[string]int, error) {
+	stop := &stopper{ctx: ctx}
+	depth := map[string]int{start: 0}
+	for layer, d := []string{start}, 1; len(layer) > 0 && (maxDepth <= 0 || d <= maxDepth); d++ {
+		next := []string{}
+		for _, n := range layer {
+			if stop.stopped() {
+				return nil, stop.err
+			}
+			for _, a := range graphMap[n] {
+				if _, seen := depth[a.To]; !seen {
+					depth[a.To] = d
+					next = append(next, a.To)
+				}
+			}
+		}
+		layer = next
+	}
+	delete(depth, start)
+	return depth, nil
+}
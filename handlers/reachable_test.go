@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindReachable(t *testing.T) {
+	ix := model.NewIndex(&model.Graph{Edges: []model.Edge{
+		{Identity: "ab", FromIdentity: "a", ToIdentity: "b"},
+		{Identity: "bc", FromIdentity: "b", ToIdentity: "c"},
+		{Identity: "ca", FromIdentity: "c", ToIdentity: "a"},
+		{Identity: "ad", FromIdentity: "a", ToIdentity: "d"},
+		{Identity: "de", FromIdentity: "d", ToIdentity: "e"},
+	}})
+
+	reachable, err := findReachable(context.Background(), "a", ix.Out, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"b": 1, "d": 1, "c": 2, "e": 2}, reachable)
+
+	reachable, err = findReachable(context.Background(), "a", ix.Out, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"b": 1, "d": 1}, reachable)
+
+	ancestors, err := findReachable(context.Background(), "e", ix.In, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"d": 1, "a": 2, "c": 3, "b": 4}, ancestors)
+
+	ancestors, err = findReachable(context.Background(), "b", ix.In, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "c": 2}, ancestors)
+}
+
+func TestFindReachable_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := findReachable(ctx, "0-0", layeredGraph(5, 3), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ListRevisionsHandler lists every revision of the graph with the :id route parameter.
+func ListRevisionsHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+
+		revisions, err := store.Revisions(graphId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.NotFoundWithMessage("Graph not found.", c)
+				return
+			}
+			response.InteralErrorWithMessage("Failed to load revisions.", c)
+			return
+		}
+
+		response.OkWithData(revisions, c)
+	}
+}
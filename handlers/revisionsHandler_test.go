@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRevisionsHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("select identity from graph where id = \\$1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"identity"}).AddRow("g"))
+	mock.ExpectQuery("select id, identity, revision, name, (.+) from graph where identity = \\$1").
+		WithArgs("g").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "revision", "name", "content_hash", "created_at"}).
+			AddRow(1, "g", 1, "Graph", "aaa", created).
+			AddRow(2, "g", 2, "Graph", "bbb", created))
+
+	router := gin.Default()
+	router.GET("/graphs/:id/revisions", ListRevisionsHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodGet, "/graphs/2/revisions", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs struct {
+		Data []model.Revision `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	assert.Len(t, rs.Data, 2)
+	assert.Equal(t, 2, rs.Data[1].Revision)
+	assert.True(t, created.Equal(rs.Data[0].CreatedAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRevisionsHandler_UnknownGraph(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("select identity from graph where id = \\$1").
+		WithArgs(8).
+		WillReturnError(sql.ErrNoRows)
+
+	router := gin.Default()
+	router.GET("/graphs/:id/revisions", ListRevisionsHandler(model.NewPostgresStore(db)))
+
+	req, err := http.NewRequest(http.MethodGet, "/graphs/8/revisions", nil)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import "context"
+
+// stopCheckInterval is the number of search steps between two looks at the
+// context, which is cheap but not free next to a single step.
+const stopCheckInterval = 256
+
+// stopper lets a search notice that its context is done.
+type stopper struct {
+	ctx   context.Context
+	steps int
+	err   error
+}
+
+// stopped reports whether the search must stop because the context is done.
+// Once it returned true it keeps doing so and err holds the context error.
+func (s *stopper) stopped() bool {
+	if s.err == nil && s.steps%stopCheckInterval == 0 {
+		s.err = s.ctx.Err()
+	}
+	s.steps++
+	return s.err != nil
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stopper{ctx: ctx}
+	assert.False(t, s.stopped())
+	cancel()
+
+	// the context is only looked at every stopCheckInterval steps
+	steps := 0
+	for !s.stopped() {
+		steps++
+	}
+	assert.Equal(t, stopCheckInterval-1, steps)
+	assert.ErrorIs(t, s.err, context.Canceled)
+	assert.True(t, s.stopped())
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+)
+
+// findShortestPath returns a path from start to end with the fewest edges, found
+// with a breadth-first search layer by layer. Among paths with that many edges
+// the first one found is returned, or the cheapest one when byCost is set.
+// ok is false when end is unreachable. Once ctx is done the search stops and
+// returns the context error.
+func findShortestPath(ctx context.Context, start string, end string, graphMap map[string][]model.Arc, byCost bool) (p foundPath, ok bool, err error) {
+	stop := &stopper{ctx: ctx}
+	hops := map[string]int{start: 0}
+	cost := map[string]float64{start: 0}
+	prev := map[string]step{}
+
+	// the layer that reaches end is finished first, it may hold a cheaper way there
+	for layer := []string{start}; len(layer) > 0; {
+		if _, found := hops[end]; found {
+			break
+		}
+		next := []string{}
+		for _, n := range layer {
+			if stop.stopped() {
+				return foundPath{}, false, stop.err
+			}
+			for _, a := range graphMap[n] {
+				c := cost[n] + a.Cost
+				h, seen := hops[a.To]
+				if seen && !(byCost && h == hops[n]+1 && c < cost[a.To]) {
+					continue
+				}
+				if !seen {
+					next = append(next, a.To)
+				}
+				hops[a.To], cost[a.To], prev[a.To] = hops[n]+1, c, step{from: n, arc: a}
+			}
+		}
+		layer = next
+	}
+
+	if _, found := hops[end]; !found {
+		return foundPath{}, false, nil
+	}
+	return tracePath(start, end, prev, cost[end]), true, nil
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindShortestPath(t *testing.T) {
+	graphMap := map[string][]model.Arc{
+		"a": {{Edge: "ab", To: "b", Cost: 5}, {Edge: "ac", To: "c", Cost: 1}, {Edge: "ax", To: "x", Cost: 1}},
+		"b": {{Edge: "be", To: "e", Cost: 5}},
+		"c": {{Edge: "ce", To: "e", Cost: 1}},
+		"x": {{Edge: "xy", To: "y", Cost: 0}},
+		"y": {{Edge: "ye", To: "e", Cost: 0}},
+	}
+
+	// the cheapest path a, x, y, e has one edge too many
+	p, ok, err := findShortestPath(context.Background(), "a", "e", graphMap, false)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b", "e"}, p.nodes)
+	assert.Equal(t, 10.0, p.cost)
+
+	p, ok, err = findShortestPath(context.Background(), "a", "e", graphMap, true)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "c", "e"}, p.nodes)
+	assert.Equal(t, 2.0, p.cost)
+	assert.Equal(t, "ce", p.arcs[1].Edge)
+
+	p, ok, err = findShortestPath(context.Background(), "a", "a", graphMap, true)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a"}, p.nodes)
+
+	_, ok, err = findShortestPath(context.Background(), "e", "a", graphMap, false)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestFindShortestPath_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok, err := findShortestPath(ctx, "0-0", "4-0", layeredGraph(5, 3), false)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ok)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// StreamLine is one line of a streamed path response. The paths of a paths or
+// kcheapest answer are sent one per line as they are found, followed by the
+// answer itself without them. Every other answer is a single line. Answer is the index the
+// answer would have in FindPathRs.Answers.
+type StreamLine struct {
+	Answer int         `json:"answer"`
+	Path   *StreamPath `json:"path,omitempty"`
+	Result *Answer     `json:"result,omitempty"`
+}
+
+type StreamPath struct {
+	Nodes []string `json:"nodes"`
+	PathDetail
+}
+
+// acceptsNDJSON reports whether the client asked for a streamed response.
+func acceptsNDJSON(c *gin.Context) bool {
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		if strings.TrimSpace(strings.SplitN(accept, ";", 2)[0]) == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// streamAnswers writes the answers of queries as NDJSON, flushing after every
+// line. It stops when the client disconnects or a write fails.
+func streamAnswers(c *gin.Context, queries []Query, ix *model.Index, limits SearchLimits) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	ctx := c.Request.Context()
+	write := func(line StreamLine) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if err := encoder.Encode(line); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	runQueries(ctx, queries, ix, limits, func(i int, p foundPath) bool {
+		return write(StreamLine{Answer: i, Path: &StreamPath{Nodes: p.nodes, PathDetail: p.detail()}})
+	}, func(i int, a Answer) bool {
+		return write(StreamLine{Answer: i, Result: &a})
+	})
+}
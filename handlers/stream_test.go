@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamTestStore(t *testing.T) *model.CachedStore {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{Identity: "g", Nodes: []model.Node{{Identity: "A"}, {Identity: "B"}, {Identity: "C"}}}
+	g.Edges = []model.Edge{
+		{Identity: "e1", FromIdentity: "A", ToIdentity: "B", Cost: 1},
+		{Identity: "e2", FromIdentity: "B", ToIdentity: "C", Cost: 1},
+		{Identity: "e3", FromIdentity: "A", ToIdentity: "C", Cost: 5},
+	}
+	assert.NoError(t, store.Create(g))
+	return store
+}
+
+func streamRequest(t *testing.T, ctx context.Context, queries []Query) *http.Request {
+	body, err := json.Marshal(FindPathRq{Queries: queries})
+	assert.NoError(t, err)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/graphs/1/paths", bytes.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+	return req
+}
+
+func TestFindPathHandler_Stream(t *testing.T) {
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(streamTestStore(t), 1, testLimits))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, streamRequest(t, context.Background(), []Query{
+		{Paths: PathRq{Start: "A", End: "C"}},
+		{Cheapest: CheapestPathRq{Start: "A", End: "C"}},
+		{Paths: PathRq{Start: "A", End: "X"}},
+	}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+
+	var lines []StreamLine
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line StreamLine
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	assert.Len(t, lines, 5)
+
+	assert.Equal(t, 0, lines[0].Answer)
+	assert.Equal(t, []string{"A", "B", "C"}, lines[0].Path.Nodes)
+	assert.Equal(t, 2.0, lines[0].Path.Cost)
+	assert.Equal(t, []string{"A", "C"}, lines[1].Path.Nodes)
+	assert.Equal(t, 0, lines[2].Answer)
+	assert.Nil(t, lines[2].Path)
+	assert.Equal(t, "A", lines[2].Result.Paths.From)
+	assert.Empty(t, lines[2].Result.Paths.AllPaths)
+
+	assert.Equal(t, 1, lines[3].Answer)
+	assert.Equal(t, []interface{}{"A", "B", "C"}, lines[3].Result.Cheapest.Path)
+
+	assert.Equal(t, 2, lines[4].Answer)
+	assert.Equal(t, ErrCodeUnknownNode, lines[4].Result.Error.Code)
+}
+
+func TestFindPathHandler_StreamDisconnected(t *testing.T) {
+	router := gin.Default()
+	router.POST("/graphs/:id/paths", FindPathHandler(streamTestStore(t), 1, testLimits))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, streamRequest(t, ctx, []Query{{Paths: PathRq{Start: "A", End: "C"}}}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+)
+
+type TopoSortRs struct {
+	Order []string `json:"order"`
+	// Levels groups Order into nodes that can be processed in parallel.
+	Levels [][]string `json:"levels"`
+}
+
+type TopoCycleRs struct {
+	Cycle []string `json:"cycle"`
+}
+
+// TopoSortHandler returns a topological order of graph :id. A graph with a cycle
+// has none, it is answered with 409 and one of its cycles instead. ?revision=
+// selects another revision of the graph.
+func TopoSortHandler(store model.GraphStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		graphId, ok := graphIdParam(c)
+		if !ok {
+			return
+		}
+		graphId, ok = resolveRevision(c, store, graphId)
+		if !ok {
+			return
+		}
+
+		order, err := store.TopoSort(graphId)
+		if err != nil {
+			modelErrorResult(err, "Graph not found.", c)
+			return
+		}
+		if order.Cycle != nil {
+			response.ConflictResult(response.CONFLICT, TopoCycleRs{Cycle: order.Cycle}, "Cycle detected.", c)
+			return
+		}
+		response.OkWithData(TopoSortRs{Order: order.Order, Levels: order.Levels}, c)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/GuohaoMa/tucowDemo/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func toposortRequest(t *testing.T, store model.GraphStore, url string, data interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.Default()
+	router.GET("/graphs/:id/toposort", TopoSortHandler(store))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if data != nil {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &struct {
+			Data interface{} `json:"data"`
+		}{data}))
+	}
+	return w
+}
+
+func TestTopoSortHandler(t *testing.T) {
+	store := model.NewCachedStore(model.NewMemoryStore(), 10)
+	g := &model.Graph{
+		Identity: "g",
+		Nodes:    []model.Node{{Identity: "c"}, {Identity: "b"}, {Identity: "a"}},
+		Edges: []model.Edge{
+			{Identity: "e1", FromIdentity: "a", ToIdentity: "c"},
+			{Identity: "e2", FromIdentity: "b", ToIdentity: "c"},
+		},
+	}
+	assert.NoError(t, store.Create(g))
+
+	var rs TopoSortRs
+	w := toposortRequest(t, store, "/graphs/1/toposort", &rs)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, TopoSortRs{Order: []string{"a", "b", "c"}, Levels: [][]string{{"a", "b"}, {"c"}}}, rs)
+
+	revisionId, err := store.CreateEdge(1, &model.Edge{Identity: "e3", FromIdentity: "c", ToIdentity: "a"})
+	assert.NoError(t, err)
+	var cycle TopoCycleRs
+	w = toposortRequest(t, store, "/graphs/"+strconv.Itoa(revisionId)+"/toposort", &cycle)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, []string{"a", "c"}, cycle.Cycle)
+
+	w = toposortRequest(t, store, "/graphs/9/toposort", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/GuohaoMa/tucowDemo/common/response"
+	"github.com/GuohaoMa/tucowDemo/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type ValidateGraphRs struct {
+	Valid      bool                   `json:"valid"`
+	Violations []validation.Violation `json:"violations"`
+}
+
+// ValidateGraphHandler runs the graph validation rules on the XML request body
+// without saving anything, so a graph file can be checked before it is uploaded.
+func ValidateGraphHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rs := ValidateGraphRs{Valid: true, Violations: []validation.Violation{}}
+		if err := validation.Validate(c.Request.Body); err != nil {
+			var report *validation.Report
+			if !errors.As(err, &report) {
+				response.ValidationFailureWithMessage(err.Error(), c)
+				return
+			}
+			rs.Valid = false
+			rs.Violations = report.Violations
+		}
+		response.OkWithData(rs, c)
+	}
+}
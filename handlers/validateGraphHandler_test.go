@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func validateGraph(t *testing.T, body string) ValidateGraphRs {
+	t.Helper()
+	router := gin.Default()
+	router.POST("/graphs/validate", ValidateGraphHandler())
+
+	req, err := http.NewRequest(http.MethodPost, "/graphs/validate", strings.NewReader(body))
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rs struct {
+		Data ValidateGraphRs `json:"data"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.NoError(t, err)
+	return rs.Data
+}
+
+func TestValidateGraphHandler_Valid(t *testing.T) {
+	rs := validateGraph(t, validGraphXML)
+	assert.True(t, rs.Valid)
+	assert.Empty(t, rs.Violations)
+}
+
+func TestValidateGraphHandler_Invalid(t *testing.T) {
+	body := `<graph>
+	<id>g1</id>
+	<name>Bad</name>
+	<nodes><node><id>a</id><name>A</name></node></nodes>
+	<edges><node><id>e1</id><from>a</from><to>z</to><cost>1</cost></node></edges>
+</graph>`
+
+	rs := validateGraph(t, body)
+	assert.False(t, rs.Valid)
+	assert.Len(t, rs.Violations, 1)
+	assert.Equal(t, 5, rs.Violations[0].Line)
+	assert.Equal(t, "To node of an edge must be predefined.", rs.Violations[0].Message)
+}
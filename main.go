@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
-
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/GuohaoMa/tucowDemo/config"
 	"github.com/GuohaoMa/tucowDemo/database"
 	"github.com/GuohaoMa/tucowDemo/handlers"
 	"github.com/GuohaoMa/tucowDemo/model"
@@ -14,36 +20,164 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout is how long requests still running on SIGINT or SIGTERM get
+// to finish before the server stops.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println("Error in loading configuration:", err)
+		os.Exit(2)
+	}
+	if err := run(cfg); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run imports the default graph and serves the API until SIGINT or SIGTERM,
+// then waits for running requests and closes the store.
+func run(cfg config.Config) error {
+	backend, closeStore, err := openStore(cfg)
+	if err != nil {
+		return fmt.Errorf("opening graph store: %w", err)
+	}
+	defer closeStore()
+	// every request goes through the cache so deletions drop the query indexes
+	store := model.NewCachedStore(backend, cfg.CacheSize)
 
-	err := validation.Validate("data/exampleTest.xml")
+	xmlData, err := os.ReadFile(cfg.GraphFile)
 	if err != nil {
-		fmt.Println("Error in validating XML file:", err)
-		return
+		return fmt.Errorf("opening XML file: %w", err)
 	}
 
-	xmlFile, err := os.Open("data/exampleTest.xml")
+	err = validation.Validate(bytes.NewReader(xmlData))
 	if err != nil {
-		fmt.Println("Error opening XML file:", err)
-		return
+		return fmt.Errorf("validating XML file:\n%w", err)
 	}
-	defer xmlFile.Close()
 
-	graph := model.Graph{Db: database.Db}
-	xmlParserDecoder := xml.NewDecoder(xmlFile)
-	xmlParserDecoder.Decode(&graph)
+	graph := model.Graph{}
+	if err := xml.Unmarshal(xmlData, &graph); err != nil {
+		return fmt.Errorf("parsing XML file: %w", err)
+	}
 
-	err = graph.Create()
+	// the default graph and its /graphs/paths alias need the import to succeed
+	created, err := store.Import(&graph)
 	if err != nil {
-		fmt.Println("Error in saving graph to database:", err)
+		return fmt.Errorf("saving graph to database: %w", err)
+	}
+	if !created {
+		fmt.Println("Graph already imported, reusing graph", graph.Id)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.RetentionDays > 0 {
+		go purgeStaleGraphs(ctx, store, time.Duration(cfg.RetentionDays)*24*time.Hour, graph.Id)
 	}
 
 	// register gin server and run
 	var r = gin.New()
-	r.Use(cors.New(cors.Config{
-		AllowAllOrigins: true,
-	}))
-	graph2 := model.Graph{Db: database.Db, Id: graph.Id}
-	r.POST("/graphs/paths", handlers.FindPathHandler(&graph2))
-	r.Run(":" + "8080")
+	r.Use(cors.New(corsConfig(cfg.CORSOrigins)))
+	limits := handlers.SearchLimits{MaxPaths: cfg.MaxPaths, QueryTimeout: cfg.QueryTimeout}
+	r.GET("/graphs", handlers.ListGraphsHandler(store))
+	r.POST("/graphs", handlers.CreateGraphHandler(store))
+	r.POST("/graphs/validate", handlers.ValidateGraphHandler())
+	r.POST("/graphs/paths", handlers.FindPathHandler(store, graph.Id, limits))
+	r.POST("/graphs/:id/paths", handlers.FindPathHandler(store, graph.Id, limits))
+	r.DELETE("/graphs/:id", handlers.DeleteGraphHandler(store))
+	r.GET("/graphs/:id/revisions", handlers.ListRevisionsHandler(store))
+	r.GET("/graphs/:id/cycles", handlers.CyclesHandler(store))
+	r.GET("/graphs/:id/toposort", handlers.TopoSortHandler(store))
+
+	r.GET("/graphs/:id/nodes", handlers.ListNodesHandler(store))
+	r.POST("/graphs/:id/nodes", handlers.CreateNodeHandler(store))
+	r.GET("/graphs/:id/nodes/:nodeId", handlers.GetNodeHandler(store))
+	r.PUT("/graphs/:id/nodes/:nodeId", handlers.UpdateNodeHandler(store))
+	r.DELETE("/graphs/:id/nodes/:nodeId", handlers.DeleteNodeHandler(store))
+
+	r.GET("/graphs/:id/edges", handlers.ListEdgesHandler(store))
+	r.POST("/graphs/:id/edges", handlers.CreateEdgeHandler(store))
+	r.GET("/graphs/:id/edges/:edgeId", handlers.GetEdgeHandler(store))
+	r.PUT("/graphs/:id/edges/:edgeId", handlers.UpdateEdgeHandler(store))
+	r.DELETE("/graphs/:id/edges/:edgeId", handlers.DeleteEdgeHandler(store))
+
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: r}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("serving HTTP: %w", err)
+	case <-ctx.Done():
+	}
+	// a second signal stops the process right away
+	stop()
+	fmt.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	return nil
+}
+
+// openStore opens the graph store selected by cfg.Store. The Postgres database
+// is migrated first. The returned function releases the store.
+func openStore(cfg config.Config) (model.GraphStore, func(), error) {
+	switch cfg.Store {
+	case config.StoreMemory:
+		return model.NewMemoryStore(), func() {}, nil
+	case config.StoreSQLite:
+		store, err := model.OpenSQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, func() { store.Close() }, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Database.ConnectTimeout)
+	db, err := database.Open(ctx, cfg.Database)
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	if err := db.Migrate(cfg.Database.MigrationsDir); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("migrating database: %w", err)
+	}
+	return model.NewPostgresStore(db.Db), func() { db.Close() }, nil
+}
+
+func corsConfig(origins []string) cors.Config {
+	for _, o := range origins {
+		if o == "*" {
+			return cors.Config{AllowAllOrigins: true}
+		}
+	}
+	c := cors.DefaultConfig()
+	c.AllowOrigins = origins
+	return c
+}
+
+// purgeStaleGraphs deletes graphs that were not queried or updated within
+// retention, once at startup and then every hour until ctx is done. The default
+// graph is kept.
+func purgeStaleGraphs(ctx context.Context, store model.GraphStore, retention time.Duration, defaultGraphId int) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		deleted, err := store.PurgeStale(time.Now().Add(-retention), []int{defaultGraphId})
+		if err != nil {
+			fmt.Println("Error in purging stale graphs:", err)
+		} else if len(deleted) > 0 {
+			fmt.Println("Purged stale graphs:", deleted)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
 }
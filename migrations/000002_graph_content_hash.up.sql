@@ -0,0 +1,2 @@
+ALTER TABLE graph ADD COLUMN IF NOT EXISTS content_hash varchar; -- SHA-256 of the graph content, used to detect re-imports
+CREATE INDEX IF NOT EXISTS graph_identity_hash ON graph (identity, content_hash); -- Lookup of an existing import by identity and hash
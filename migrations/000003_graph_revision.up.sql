@@ -0,0 +1,7 @@
+ALTER TABLE graph ADD COLUMN IF NOT EXISTS revision integer NOT NULL DEFAULT 1; -- Revision number within graphs sharing the same identity
+ALTER TABLE graph ADD COLUMN IF NOT EXISTS created_at timestamp NOT NULL DEFAULT now(); -- Time the revision was uploaded
+-- Number the graphs imported before revisions existed in insertion order
+UPDATE graph g SET revision = r.rn
+FROM (SELECT id, row_number() OVER (PARTITION BY identity ORDER BY id) AS rn FROM graph) r
+WHERE g.id = r.id;
+ALTER TABLE graph ADD CONSTRAINT graph_revision_key UNIQUE (identity, revision); -- Unique constraint on identity and revision
@@ -0,0 +1,12 @@
+-- Deleting a graph removes its nodes and edges, deleting a node removes its edges
+ALTER TABLE node
+    DROP CONSTRAINT IF EXISTS node_graph_id_fkey,
+    ADD CONSTRAINT node_graph_id_fkey FOREIGN KEY (graph_id) REFERENCES graph(id) ON DELETE CASCADE;
+ALTER TABLE edge
+    DROP CONSTRAINT IF EXISTS edge_from_id_fkey,
+    ADD CONSTRAINT edge_from_id_fkey FOREIGN KEY (from_id) REFERENCES node(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS edge_to_id_fkey,
+    ADD CONSTRAINT edge_to_id_fkey FOREIGN KEY (to_id) REFERENCES node(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS edge_graph_id_fkey,
+    ADD CONSTRAINT edge_graph_id_fkey FOREIGN KEY (graph_id) REFERENCES graph(id) ON DELETE CASCADE;
+ALTER TABLE graph ADD COLUMN IF NOT EXISTS last_used_at timestamp NOT NULL DEFAULT now(); -- Last time the graph was queried or updated, used by the retention policy
@@ -0,0 +1,9 @@
+ALTER TABLE node ADD COLUMN IF NOT EXISTS removed_in integer; -- First revision of the graph identity the node is no longer part of
+ALTER TABLE edge ADD COLUMN IF NOT EXISTS removed_in integer; -- First revision of the graph identity the edge is no longer part of
+-- Revisions saved so far hold full copies, so their rows end with the next revision
+UPDATE node n SET removed_in = g.revision + 1
+FROM graph g
+WHERE g.id = n.graph_id AND EXISTS (SELECT 1 FROM graph l WHERE l.identity = g.identity AND l.revision > g.revision);
+UPDATE edge e SET removed_in = g.revision + 1
+FROM graph g
+WHERE g.id = e.graph_id AND EXISTS (SELECT 1 FROM graph l WHERE l.identity = g.identity AND l.revision > g.revision);
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CachedStore wraps a GraphStore and keeps the Index of the graphs queried
+// through it in memory, at most capacity of them; the least recently used index
+// is dropped first. Every revision has its own graph id, so an index is keyed by
+// the id of the revision it was built from. Stored revisions never change, node
+// and edge mutations save new ones, so an index only goes away when its graph is
+// deleted through the CachedStore or evicted.
+//
+// A CachedStore is safe for concurrent use.
+type CachedStore struct {
+	GraphStore
+
+	mu       sync.Mutex
+	capacity int
+	// entries holds a *cacheEntry per cached graph, most recently used first.
+	entries *list.List
+	byId    map[int]*list.Element
+	// generation counts the invalidations, so a load that raced with a deletion
+	// is not cached.
+	generation uint64
+}
+
+type cacheEntry struct {
+	id       int
+	ix       *Index
+	markedAt time.Time
+}
+
+// markUsedInterval is how often MarkUsed reaches the wrapped store per graph.
+// Retention works in days, so recording every query would only add writes.
+const markUsedInterval = time.Minute
+
+// NewCachedStore caches the indexes of at most capacity graphs of store.
+func NewCachedStore(store GraphStore, capacity int) *CachedStore {
+	return &CachedStore{GraphStore: store, capacity: capacity, entries: list.New(), byId: make(map[int]*list.Element)}
+}
+
+// Index returns the index of graph id, loading the graph when it is not cached.
+// It returns sql.ErrNoRows when the graph does not exist.
+func (s *CachedStore) Index(id int) (*Index, error) {
+	s.mu.Lock()
+	if e, ok := s.byId[id]; ok {
+		s.entries.MoveToFront(e)
+		s.mu.Unlock()
+		return e.Value.(*cacheEntry).ix, nil
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	g, err := s.GraphStore.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	ix := NewIndex(g)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.byId[id]; ok {
+		return e.Value.(*cacheEntry).ix, nil
+	}
+	if s.generation == generation {
+		s.byId[id] = s.entries.PushFront(&cacheEntry{id: id, ix: ix})
+		for s.entries.Len() > s.capacity {
+			s.remove(s.entries.Back())
+		}
+	}
+	return ix, nil
+}
+
+// remove drops a cache entry. s.mu must be held.
+func (s *CachedStore) remove(e *list.Element) {
+	s.entries.Remove(e)
+	delete(s.byId, e.Value.(*cacheEntry).id)
+}
+
+// FindCycles searches the cached index of graph id.
+func (s *CachedStore) FindCycles(id int, limit int) ([][]string, error) {
+	ix, err := s.Index(id)
+	if err != nil {
+		return nil, err
+	}
+	return ix.Cycles(limit), nil
+}
+
+// TopoSort sorts the cached index of graph id.
+func (s *CachedStore) TopoSort(id int) (TopoOrder, error) {
+	ix, err := s.Index(id)
+	if err != nil {
+		return TopoOrder{}, err
+	}
+	return ix.TopoSort(), nil
+}
+
+// MarkUsed passes the call on to the wrapped store at most once per
+// markUsedInterval for every cached graph.
+func (s *CachedStore) MarkUsed(id int) error {
+	now := time.Now()
+	s.mu.Lock()
+	if e, ok := s.byId[id]; ok {
+		entry := e.Value.(*cacheEntry)
+		if now.Sub(entry.markedAt) < markUsedInterval {
+			s.mu.Unlock()
+			return nil
+		}
+		entry.markedAt = now
+	}
+	s.mu.Unlock()
+	return s.GraphStore.MarkUsed(id)
+}
+
+// Invalidate drops the cached indexes of ids.
+func (s *CachedStore) Invalidate(ids ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if e, ok := s.byId[id]; ok {
+			s.remove(e)
+		}
+	}
+	s.generation++
+}
+
+func (s *CachedStore) Delete(id int) ([]int, error) {
+	deleted, err := s.GraphStore.Delete(id)
+	s.Invalidate(deleted...)
+	return deleted, err
+}
+
+func (s *CachedStore) PurgeStale(before time.Time, keep []int) ([]int, error) {
+	deleted, err := s.GraphStore.PurgeStale(before, keep)
+	s.Invalidate(deleted...)
+	return deleted, err
+}
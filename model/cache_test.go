@@ -0,0 +1,154 @@
+package model
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the graph loads that reach the wrapped store.
+type countingStore struct {
+	GraphStore
+	mu    sync.Mutex
+	loads int
+	marks int
+}
+
+func (s *countingStore) Get(id int) (*Graph, error) {
+	s.mu.Lock()
+	s.loads++
+	s.mu.Unlock()
+	return s.GraphStore.Get(id)
+}
+
+func (s *countingStore) MarkUsed(id int) error {
+	s.marks++
+	return s.GraphStore.MarkUsed(id)
+}
+
+func TestCachedStore_Index(t *testing.T) {
+	inner := &countingStore{GraphStore: NewMemoryStore()}
+	store := NewCachedStore(inner, 10)
+	g := testGraph()
+	require.NoError(t, store.Create(g))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ix, err := store.Index(g.Id)
+			assert.NoError(t, err)
+			assert.Equal(t, []Arc{{Edge: "e1", To: "b", Cost: 1.5}}, ix.Out["a"])
+		}()
+	}
+	wg.Wait()
+	loads := inner.loads
+	_, err := store.Index(g.Id)
+	require.NoError(t, err)
+	assert.Equal(t, loads, inner.loads)
+
+	revisionId, err := store.CreateEdge(g.Id, &Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "c", Cost: 1})
+	require.NoError(t, err)
+	ix, err := store.Index(g.Id)
+	require.NoError(t, err)
+	assert.Equal(t, loads, inner.loads)
+	assert.Len(t, ix.Out["a"], 1)
+	ix, err = store.Index(revisionId)
+	require.NoError(t, err)
+	assert.Equal(t, loads+1, inner.loads)
+	assert.Len(t, ix.Out["a"], 2)
+
+	_, err = store.Delete(g.Id)
+	require.NoError(t, err)
+	_, err = store.Index(g.Id)
+	assert.Error(t, err)
+	_, err = store.Index(revisionId)
+	assert.Error(t, err)
+	assert.Zero(t, store.entries.Len())
+}
+
+func TestCachedStore_MarkUsedIsRateLimited(t *testing.T) {
+	inner := &countingStore{GraphStore: NewMemoryStore()}
+	store := NewCachedStore(inner, 10)
+	require.NoError(t, store.Create(testGraph()))
+	_, err := store.Index(1)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.MarkUsed(1))
+	}
+	assert.Equal(t, 1, inner.marks)
+
+	store.byId[1].Value.(*cacheEntry).markedAt = time.Now().Add(-markUsedInterval)
+	require.NoError(t, store.MarkUsed(1))
+	assert.Equal(t, 2, inner.marks)
+}
+
+func TestCachedStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	inner := &countingStore{GraphStore: NewMemoryStore()}
+	store := NewCachedStore(inner, 2)
+	for i := 0; i < 3; i++ {
+		g := testGraph()
+		g.Identity += string(rune('a' + i))
+		require.NoError(t, store.Create(g))
+	}
+	load := func(id int) {
+		_, err := store.Index(id)
+		require.NoError(t, err)
+	}
+
+	load(1)
+	load(2)
+	load(1)
+	load(3) // drops 2, used less recently than 1
+	assert.Equal(t, 3, inner.loads)
+	load(1)
+	assert.Equal(t, 3, inner.loads)
+	load(2)
+	assert.Equal(t, 4, inner.loads)
+	assert.Equal(t, 2, store.entries.Len())
+
+	_, err := store.Delete(2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.entries.Len())
+	assert.Len(t, store.byId, 1)
+}
+
+func TestNewIndex(t *testing.T) {
+	g := testGraph()
+	g.Edges = append(g.Edges, Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "a", Cost: 1})
+	ix := NewIndex(g)
+	assert.Equal(t, []Arc{{Edge: "e1", To: "b", Cost: 1.5}}, ix.Out["a"])
+	assert.Equal(t, []Arc{{Edge: "e3", To: "c", Cost: 0}}, ix.In["a"])
+	assert.Equal(t, []Arc{{Edge: "e1", To: "a", Cost: 1.5}}, ix.In["b"])
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, ix.Nodes)
+	assert.Equal(t, map[string]bool{"a": true}, ix.SelfLoops)
+	assert.False(t, ix.Acyclic)
+
+	// a self-loop is a cycle as well
+	g.Edges = g.Edges[1:]
+	assert.False(t, NewIndex(g).Acyclic)
+	g.Edges = g.Edges[:len(g.Edges)-1]
+	assert.True(t, NewIndex(g).Acyclic)
+}
+
+func TestNewIndex_Version(t *testing.T) {
+	g := testGraph()
+	assert.Equal(t, NewIndex(g).Version, NewIndex(testGraph()).Version)
+	g.Edges[1].Cost = 3
+	assert.NotEqual(t, NewIndex(testGraph()).Version, NewIndex(g).Version)
+	// the arc order decides the path order, so it is part of the version
+	g = testGraph()
+	g.Edges = append(g.Edges, Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "c", Cost: 1})
+	swapped := testGraph()
+	swapped.Edges = append([]Edge{{Identity: "e4", FromIdentity: "a", ToIdentity: "c", Cost: 1}}, swapped.Edges...)
+	assert.NotEqual(t, NewIndex(g).Version, NewIndex(swapped).Version)
+}
+
+func TestIndex_Cycles(t *testing.T) {
+	ix := NewIndex(testGraph())
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, ix.Cycles(0))
+}
@@ -0,0 +1,183 @@
+package model
+
+import "sort"
+
+// elementaryCycles returns the elementary cycles of the graph given by out, at
+// most limit of them when limit is positive. Strongly connected components are
+// found with Tarjan's algorithm and the cycles inside them with Johnson's
+// algorithm. Each cycle is a list of node identities in edge order, starting at
+// its smallest identity; the edge back to the first node is implied. out has no
+// self-loops, Index.Cycles reports them.
+func elementaryCycles(out map[string][]Arc, limit int) [][]string {
+	names := make([]string, 0, len(out))
+	for n := range out {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	index := make(map[string]int, len(names))
+	for i, n := range names {
+		index[n] = i
+	}
+	adj := make([][]int, len(names))
+	for i, n := range names {
+		for _, a := range out[n] {
+			if j, ok := index[a.To]; ok && j != i {
+				adj[i] = append(adj[i], j)
+			}
+		}
+		sort.Ints(adj[i])
+	}
+
+	j := johnson{adj: adj, limit: limit, result: [][]string{}, names: names}
+	all := make([]bool, len(names))
+	for i := range all {
+		all[i] = true
+	}
+	pending := stronglyConnected(adj, all)
+	for len(pending) > 0 && !j.full() {
+		scc := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if len(scc) < 2 {
+			continue
+		}
+		// every cycle through the smallest node of the component is found
+		// first, then the node is removed and the rest split up again
+		start := scc[0]
+		for _, v := range scc {
+			start = min(start, v)
+		}
+		in := make([]bool, len(names))
+		for _, v := range scc {
+			in[v] = true
+		}
+		j.circuits(start, in)
+		in[start] = false
+		pending = append(pending, stronglyConnected(adj, in)...)
+	}
+	return j.result
+}
+
+// johnson holds the state of Johnson's circuit search within one component.
+type johnson struct {
+	adj    [][]int
+	names  []string
+	limit  int
+	result [][]string
+
+	in      []bool
+	start   int
+	stack   []int
+	blocked []bool
+	b       []map[int]bool
+}
+
+func (j *johnson) full() bool {
+	return j.limit > 0 && len(j.result) >= j.limit
+}
+
+func (j *johnson) circuits(start int, in []bool) {
+	j.in, j.start, j.stack = in, start, j.stack[:0]
+	j.blocked = make([]bool, len(j.adj))
+	j.b = make([]map[int]bool, len(j.adj))
+	j.circuit(start)
+}
+
+func (j *johnson) circuit(v int) bool {
+	found := false
+	j.stack = append(j.stack, v)
+	j.blocked[v] = true
+	for _, w := range j.adj[v] {
+		if !j.in[w] || j.full() {
+			continue
+		}
+		if w == j.start {
+			cycle := make([]string, len(j.stack))
+			for i, n := range j.stack {
+				cycle[i] = j.names[n]
+			}
+			j.result = append(j.result, cycle)
+			found = true
+		} else if !j.blocked[w] && j.circuit(w) {
+			found = true
+		}
+	}
+	if found {
+		j.unblock(v)
+	} else {
+		for _, w := range j.adj[v] {
+			if j.in[w] {
+				if j.b[w] == nil {
+					j.b[w] = make(map[int]bool)
+				}
+				j.b[w][v] = true
+			}
+		}
+	}
+	j.stack = j.stack[:len(j.stack)-1]
+	return found
+}
+
+func (j *johnson) unblock(v int) {
+	j.blocked[v] = false
+	for w := range j.b[v] {
+		delete(j.b[v], w)
+		if j.blocked[w] {
+			j.unblock(w)
+		}
+	}
+}
+
+// stronglyConnected returns the strongly connected components of the subgraph
+// of adj induced by the nodes marked in, using Tarjan's algorithm.
+func stronglyConnected(adj [][]int, in []bool) [][]int {
+	t := tarjan{adj: adj, in: in, index: make([]int, len(adj)), low: make([]int, len(adj)), onStack: make([]bool, len(adj))}
+	for v := range adj {
+		if in[v] && t.index[v] == 0 {
+			t.visit(v)
+		}
+	}
+	return t.components
+}
+
+type tarjan struct {
+	adj        [][]int
+	in         []bool
+	next       int
+	index      []int // 1-based visit order, 0 for unvisited nodes
+	low        []int
+	stack      []int
+	onStack    []bool
+	components [][]int
+}
+
+func (t *tarjan) visit(v int) {
+	t.next++
+	t.index[v], t.low[v] = t.next, t.next
+	t.stack = append(t.stack, v)
+	t.onStack[v] = true
+	for _, w := range t.adj[v] {
+		if !t.in[w] {
+			continue
+		}
+		if t.index[w] == 0 {
+			t.visit(w)
+			t.low[v] = min(t.low[v], t.low[w])
+		} else if t.onStack[w] {
+			t.low[v] = min(t.low[v], t.index[w])
+		}
+	}
+	if t.low[v] != t.index[v] {
+		return
+	}
+	var component []int
+	for {
+		w := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[w] = false
+		component = append(component, w)
+		if w == v {
+			break
+		}
+	}
+	t.components = append(t.components, component)
+}
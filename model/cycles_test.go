@@ -0,0 +1,80 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func arcs(edges ...string) map[string][]Arc {
+	out := map[string][]Arc{}
+	for _, e := range edges {
+		ends := strings.Split(e, ">")
+		out[ends[0]] = append(out[ends[0]], Arc{To: ends[1], Cost: 1})
+	}
+	return out
+}
+
+func sortedCycles(cycles [][]string) []string {
+	result := make([]string, len(cycles))
+	for i, c := range cycles {
+		result[i] = strings.Join(c, ",")
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestElementaryCycles_SimilarIdentities(t *testing.T) {
+	// "a" is a substring of "ab", which must not be mistaken for a revisit
+	cycles := elementaryCycles(arcs("a>ab", "ab>b", "b>a"), 0)
+	assert.Equal(t, [][]string{{"a", "ab", "b"}}, cycles)
+}
+
+func TestElementaryCycles_Acyclic(t *testing.T) {
+	assert.Empty(t, elementaryCycles(arcs("a>b", "b>c", "a>c", "c>c"), 0))
+}
+
+func TestElementaryCycles_SeveralComponents(t *testing.T) {
+	cycles := elementaryCycles(arcs("a>b", "b>a", "b>c", "c>d", "d>e", "e>c", "d>c"), 0)
+	assert.Equal(t, []string{"a,b", "c,d", "c,d,e"}, sortedCycles(cycles))
+}
+
+func TestElementaryCycles_CompleteGraph(t *testing.T) {
+	var edges []string
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			if i != j {
+				edges = append(edges, strconv.Itoa(i)+">"+strconv.Itoa(j))
+			}
+		}
+	}
+	// a complete directed graph on 5 nodes has 10+20+30+24 elementary cycles
+	cycles := elementaryCycles(arcs(edges...), 0)
+	assert.Len(t, cycles, 84)
+	assert.Len(t, sortedCycles(cycles), 84)
+	seen := map[string]bool{}
+	for _, c := range sortedCycles(cycles) {
+		assert.False(t, seen[c], "cycle %s reported twice", c)
+		seen[c] = true
+	}
+
+	assert.Len(t, elementaryCycles(arcs(edges...), 7), 7)
+}
+
+func TestIndex_CyclesSelfLoops(t *testing.T) {
+	g := &Graph{Nodes: []Node{{Identity: "a"}, {Identity: "b"}, {Identity: "c"}}}
+	for _, e := range [][2]string{{"b", "b"}, {"a", "a"}, {"a", "c"}, {"c", "a"}} {
+		g.Edges = append(g.Edges, Edge{Identity: e[0] + e[1], FromIdentity: e[0], ToIdentity: e[1]})
+	}
+	ix := NewIndex(g)
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"a", "c"}}, ix.Cycles(0))
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, ix.Cycles(2))
+	assert.Equal(t, [][]string{{"a"}}, ix.Cycles(1))
+
+	// a self-loop alone is a cycle too
+	g.Edges = g.Edges[:1]
+	assert.Equal(t, [][]string{{"b"}}, NewIndex(g).Cycles(0))
+}
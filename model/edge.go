@@ -1,5 +1,10 @@
 package model
 
+import (
+	"database/sql"
+	"errors"
+)
+
 type Edge struct {
 	Id           int
 	Identity     string `xml:"id"`
@@ -9,3 +14,160 @@ type Edge struct {
 	ToIdentity   string  `xml:"to"`
 	Cost         float64 `xml:"cost"`
 }
+
+const edgeColumns = "e.id, e.identity, e.from_id, e.from_identity, e.to_id, e.to_identity, e.cost"
+
+// edgeRows selects the edges of revision $2 of graph identity $1.
+const edgeRows = "edge e join graph g on g.id = e.graph_id where g.identity = $1 and g.revision <= $2 and (e.removed_in is null or e.removed_in > $2)"
+
+// liveEdges selects the edges of the latest revision of graph identity $1.
+const liveEdges = "edge e join graph g on g.id = e.graph_id where g.identity = $1 and e.removed_in is null"
+
+func scanEdge(row interface{ Scan(...interface{}) error }, e *Edge) error {
+	return row.Scan(&e.Id, &e.Identity, &e.FromId, &e.FromIdentity, &e.ToId, &e.ToIdentity, &e.Cost)
+}
+
+// ListEdges returns the edges of graph graphId ordered by identity.
+func (s *sqlStore) ListEdges(graphId int) ([]Edge, error) {
+	graph, revision, err := graphRevision(s.Db, graphId)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Db.Query("select "+edgeColumns+" from "+edgeRows+" order by e.identity", graph, revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []Edge{}
+	for rows.Next() {
+		e := Edge{}
+		if err := scanEdge(rows, &e); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// GetEdge returns the edge with the given identity, or sql.ErrNoRows.
+func (s *sqlStore) GetEdge(graphId int, identity string) (Edge, error) {
+	e := Edge{}
+	graph, revision, err := graphRevision(s.Db, graphId)
+	if err != nil {
+		return e, err
+	}
+	err = scanEdge(s.Db.QueryRow("select "+edgeColumns+" from "+edgeRows+" and e.identity = $3", graph, revision, identity), &e)
+	return e, err
+}
+
+// CreateEdge adds e to a new revision of graph graphId and returns the id of the
+// revision. Both end nodes must exist and the cost must be non-negative. e.Id,
+// e.FromId and e.ToId are set on success.
+func (s *sqlStore) CreateEdge(graphId int, e *Edge) (int, error) {
+	if e.Cost < 0 {
+		return 0, ErrNegativeCost
+	}
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	for _, end := range []struct {
+		identity string
+		id       *int
+	}{{e.FromIdentity, &e.FromId}, {e.ToIdentity, &e.ToId}} {
+		id, err := liveNode(tx, r.identity, end.identity)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUnknownNode
+		}
+		if err != nil {
+			return 0, err
+		}
+		*end.id = id
+	}
+	// the unique constraints only cover rows added by the same revision
+	var id int
+	err = tx.QueryRow("select e.id from "+liveEdges+" and e.identity = $2", r.identity, e.Identity).Scan(&id)
+	if err == nil {
+		return 0, ErrDuplicateEdge
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	// node ids belong to a single graph identity
+	err = tx.QueryRow("select id from edge where removed_in is null and from_id = $1 and to_id = $2", e.FromId, e.ToId).Scan(&id)
+	if err == nil {
+		return 0, ErrDuplicateEdgeNodes
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	err = tx.QueryRow("insert into edge (identity, from_id, from_identity, to_id, to_identity, cost, graph_id) values ($1, $2, $3, $4, $5, $6, $7) returning id",
+		e.Identity, e.FromId, e.FromIdentity, e.ToId, e.ToIdentity, e.Cost, r.id).Scan(&e.Id)
+	if err != nil {
+		return 0, s.dialect.constraintError(err)
+	}
+	return r.id, tx.Commit()
+}
+
+// UpdateEdge changes the cost of the edge with identity e.Identity in a new
+// revision of graph graphId, fills in the remaining fields of e and returns the
+// id of the revision. The changed edge is a new row, so e.Id changes too.
+func (s *sqlStore) UpdateEdge(graphId int, e *Edge) (int, error) {
+	if e.Cost < 0 {
+		return 0, ErrNegativeCost
+	}
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	old := Edge{}
+	if err := scanEdge(tx.QueryRow("select "+edgeColumns+" from "+liveEdges+" and e.identity = $2", r.identity, e.Identity), &old); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update edge set removed_in = $1 where id = $2", r.revision, old.Id); err != nil {
+		return 0, err
+	}
+	old.Cost = e.Cost
+	err = tx.QueryRow("insert into edge (identity, from_id, from_identity, to_id, to_identity, cost, graph_id) values ($1, $2, $3, $4, $5, $6, $7) returning id",
+		old.Identity, old.FromId, old.FromIdentity, old.ToId, old.ToIdentity, old.Cost, r.id).Scan(&old.Id)
+	if err != nil {
+		return 0, s.dialect.constraintError(err)
+	}
+	*e = old
+	return r.id, tx.Commit()
+}
+
+// DeleteEdge removes the edge with the given identity from a new revision of
+// graph graphId and returns the id of the revision.
+func (s *sqlStore) DeleteEdge(graphId int, identity string) (int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("update edge set removed_in = $1 where removed_in is null and identity = $2 and graph_id in (select id from graph where identity = $3)", r.revision, identity, r.identity)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return r.id, tx.Commit()
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_CreateEdge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 1)
+	expectLiveNode(mock, "node-2", 2)
+	mock.ExpectQuery("select e.id from edge e join graph g on g.id = e.graph_id where g.identity = \\$1 and e.removed_in is null and e.identity = \\$2").
+		WithArgs("graph-1", "edge-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("select id from edge where removed_in is null and from_id = \\$1 and to_id = \\$2").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("insert into edge").
+		WithArgs("edge-2", 1, "node-1", 2, "node-2", 3.5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectCommit()
+
+	store := NewPostgresStore(db)
+	edge := Edge{Identity: "edge-2", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 3.5}
+	revisionId, err := store.CreateEdge(1, &edge)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revisionId)
+	assert.Equal(t, 8, edge.Id)
+	assert.Equal(t, 1, edge.FromId)
+	assert.Equal(t, 2, edge.ToId)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateEdgeUnknownNode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "missing", 0)
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateEdge(1, &Edge{Identity: "edge-2", FromIdentity: "missing", ToIdentity: "node-2", Cost: 1})
+	assert.ErrorIs(t, err, ErrUnknownNode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateEdgeDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 1)
+	expectLiveNode(mock, "node-2", 2)
+	mock.ExpectQuery("select e.id from edge e").
+		WithArgs("graph-1", "edge-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateEdge(1, &Edge{Identity: "edge-1", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 1})
+	assert.ErrorIs(t, err, ErrDuplicateEdge)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateEdgeDuplicateEndpoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 1)
+	expectLiveNode(mock, "node-2", 2)
+	mock.ExpectQuery("select e.id from edge e").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("select id from edge where removed_in is null and from_id = \\$1 and to_id = \\$2").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateEdge(1, &Edge{Identity: "edge-2", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 1})
+	assert.ErrorIs(t, err, ErrDuplicateEdgeNodes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateEdgeConstraint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 1)
+	expectLiveNode(mock, "node-2", 2)
+	mock.ExpectQuery("select e.id from edge e").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("select id from edge").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("insert into edge").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "edge_key2"})
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateEdge(1, &Edge{Identity: "edge-2", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 1})
+	assert.ErrorIs(t, err, ErrDuplicateEdgeNodes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateEdgeNegativeCost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateEdge(1, &Edge{Identity: "edge-2", FromIdentity: "node-1", ToIdentity: "node-2", Cost: -1})
+	assert.ErrorIs(t, err, ErrNegativeCost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_UpdateEdge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	mock.ExpectQuery("select e.id, e.identity, e.from_id, e.from_identity, e.to_id, e.to_identity, e.cost from edge e join graph g on g.id = e.graph_id where g.identity = \\$1 and e.removed_in is null and e.identity = \\$2").
+		WithArgs("graph-1", "edge-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "from_id", "from_identity", "to_id", "to_identity", "cost"}).
+			AddRow(1, "edge-1", 1, "node-1", 2, "node-2", 3.0))
+	mock.ExpectExec("update edge set removed_in = \\$1 where id = \\$2").
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("insert into edge").
+		WithArgs("edge-1", 1, "node-1", 2, "node-2", 7.0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	store := NewPostgresStore(db)
+	edge := Edge{Identity: "edge-1", Cost: 7}
+	revisionId, err := store.UpdateEdge(1, &edge)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revisionId)
+	assert.Equal(t, 5, edge.Id)
+	assert.Equal(t, "node-1", edge.FromIdentity)
+	assert.Equal(t, "node-2", edge.ToIdentity)
+	assert.Equal(t, 7.0, edge.Cost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_DeleteEdgeNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	mock.ExpectExec("update edge set removed_in = \\$1 where removed_in is null and identity = \\$2 and graph_id in \\(select id from graph where identity = \\$3\\)").
+		WithArgs(2, "edge-9", "graph-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.DeleteEdge(1, "edge-9")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
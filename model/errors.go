@@ -0,0 +1,19 @@
+package model
+
+import "errors"
+
+// Errors returned by node and edge mutations. They mirror the XML validation
+// rules and the unique constraints of the schema.
+var (
+	ErrDuplicateNode      = errors.New("All nodes must have different <id> tags.")
+	ErrDuplicateEdge      = errors.New("All edges must have different <id> tags.")
+	ErrDuplicateEdgeNodes = errors.New("There can be only one edge between the same <from> and <to> nodes.")
+	ErrUnknownNode        = errors.New("From and to nodes of an edge must be predefined.")
+	ErrNegativeCost       = errors.New("Cost of an edge must be non-negative.")
+	// ErrRevisionConflict is returned when another change saved the same revision
+	// of a graph first.
+	ErrRevisionConflict = errors.New("The graph was changed at the same time, please retry.")
+	// ErrNotLatestRevision is returned when a change is made to a revision of a
+	// graph that was changed since.
+	ErrNotLatestRevision = errors.New("Only the latest revision of a graph can be changed.")
+)
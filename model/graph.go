@@ -1,132 +1,278 @@
 package model
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
-
-	"github.com/lib/pq"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Graph struct {
-	Db       *sql.DB
-	XMLName  xml.Name `xml:"graph"`
-	Id       int
-	Identity string `xml:"id"`
-	Name     string `xml:"name"`
-	Nodes    []Node `xml:"nodes>node"`
-	Edges    []Edge `xml:"edges>node"`
+	XMLName     xml.Name `xml:"graph"`
+	Id          int
+	Identity    string    `xml:"id"`
+	Name        string    `xml:"name"`
+	ContentHash string    `xml:"-"`
+	Revision    int       `xml:"-"`
+	CreatedAt   time.Time `xml:"-"`
+	Nodes       []Node    `xml:"nodes>node"`
+	Edges       []Edge    `xml:"edges>node"`
+}
+
+// Hash returns a SHA-256 of the graph content. Nodes and edges are sorted first,
+// so documents that differ only in formatting or element order hash the same.
+func (g *Graph) Hash() string {
+	nodes := make([]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		nodes[i] = fmt.Sprintf("%q %q", n.Identity, n.Name)
+	}
+	sort.Strings(nodes)
+	edges := make([]string, len(g.Edges))
+	for i, e := range g.Edges {
+		edges[i] = fmt.Sprintf("%q %q %q %s", e.Identity, e.FromIdentity, e.ToIdentity, strconv.FormatFloat(e.Cost, 'g', -1, 64))
+	}
+	sort.Strings(edges)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q\n", g.Identity, g.Name)
+	for _, n := range nodes {
+		fmt.Fprintln(h, "node", n)
+	}
+	for _, e := range edges {
+		fmt.Fprintln(h, "edge", e)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dialect holds what differs between the SQL databases backing sqlStore.
+type dialect struct {
+	// forUpdate is appended to selects whose rows are about to be changed.
+	forUpdate string
+	// constraintError translates unique constraint violations into model errors.
+	constraintError func(error) error
+	// timeArg converts a time into a query argument comparable with timestamp columns.
+	timeArg func(time.Time) interface{}
+}
+
+// sqlStore implements GraphStore on top of database/sql. It is shared by the
+// Postgres and SQLite stores, which only differ in their dialect.
+type sqlStore struct {
+	Db      *sql.DB
+	dialect dialect
 }
 
-func (g *Graph) Create() error {
-	err := g.Db.QueryRow("insert into graph (identity, name) values ($1, $2) returning id", g.Identity, g.Name).Scan(&g.Id)
+func (s *sqlStore) Import(g *Graph) (created bool, err error) {
+	hash := g.Hash()
+	var id, revision int
+	var latestHash sql.NullString
+	err = s.Db.QueryRow("select id, revision, content_hash from graph where identity = $1 order by revision desc limit 1", g.Identity).Scan(&id, &revision, &latestHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && latestHash.String == hash {
+		g.Id, g.Revision, g.ContentHash = id, revision, hash
+		return false, s.MarkUsed(id)
+	}
+	return true, s.Create(g)
+}
+
+// insertBatchSize is the number of rows sent per multi-row insert, which keeps
+// every statement well below the 65535 bind parameter limit of Postgres.
+const insertBatchSize = 1000
+
+// Create saves the graph with its nodes and edges in a single transaction, so a
+// failure leaves nothing behind. Nodes and edges are written with multi-row
+// inserts; the ones of the previous revision are marked as removed.
+func (s *sqlStore) Create(g *Graph) error {
+	tx, err := s.Db.Begin()
 	if err != nil {
 		return err
 	}
-	if len(g.Nodes) > 0 {
-		for i, n := range g.Nodes {
-			err := g.Db.QueryRow("insert into node (identity, name, graph_id) values ($1, $2, $3) returning id", n.Identity, n.Name, g.Id).Scan(&g.Nodes[i].Id)
-			if err != nil {
-				return err
-			}
+	defer tx.Rollback()
+
+	g.ContentHash = g.Hash()
+	// the identity is passed twice: Postgres cannot type a parameter used both as
+	// a selected value and in a comparison
+	err = tx.QueryRow(`insert into graph (identity, name, content_hash, revision)
+		select $1, $2, $3, coalesce(max(revision), 0) + 1 from graph where identity = $4
+		returning id, revision, created_at`, g.Identity, g.Name, g.ContentHash, g.Identity).Scan(&g.Id, &g.Revision, &g.CreatedAt)
+	if err != nil {
+		return s.dialect.constraintError(err)
+	}
+	// an upload replaces the content of the previous revision
+	for _, table := range []string{"node", "edge"} {
+		_, err = tx.Exec("update "+table+" set removed_in = $1 where removed_in is null and graph_id in (select id from graph where identity = $2 and id <> $3)", g.Revision, g.Identity, g.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	nodeIds := make(map[string]int, len(g.Nodes))
+	for start := 0; start < len(g.Nodes); start += insertBatchSize {
+		batch := g.Nodes[start:min(start+insertBatchSize, len(g.Nodes))]
+		args := make([]interface{}, 0, len(batch)*3)
+		for _, n := range batch {
+			args = append(args, n.Identity, n.Name, g.Id)
+		}
+		query := "insert into node (identity, name, graph_id) values " + valuesList(len(batch), 3) + " returning id, identity"
+		if err := scanIds(tx, nodeIds, query, args...); err != nil {
+			return s.dialect.constraintError(err)
 		}
 	}
-	if len(g.Edges) > 0 {
-		for i, e := range g.Edges {
-			var fromNodeId, toNodeId int
-			var fromNodeIdentity, toNodeIdentity string
-			err := g.Db.QueryRow("select id, identity from node where identity = $1 and graph_id = $2", e.FromIdentity, g.Id).Scan(&fromNodeId, &fromNodeIdentity)
-			if err != nil {
-				return err
+	for i, n := range g.Nodes {
+		g.Nodes[i].Id = nodeIds[n.Identity]
+	}
+
+	edgeIds := make(map[string]int, len(g.Edges))
+	for start := 0; start < len(g.Edges); start += insertBatchSize {
+		batch := g.Edges[start:min(start+insertBatchSize, len(g.Edges))]
+		args := make([]interface{}, 0, len(batch)*7)
+		for _, e := range batch {
+			fromId, ok := nodeIds[e.FromIdentity]
+			if !ok {
+				return fmt.Errorf("edge %q references unknown node %q", e.Identity, e.FromIdentity)
 			}
-			err = g.Db.QueryRow("select id, identity from node where identity = $1 and graph_id = $2", e.ToIdentity, g.Id).Scan(&toNodeId, &toNodeIdentity)
-			if err != nil {
-				return err
+			toId, ok := nodeIds[e.ToIdentity]
+			if !ok {
+				return fmt.Errorf("edge %q references unknown node %q", e.Identity, e.ToIdentity)
 			}
-			err = g.Db.QueryRow("insert into edge (identity, from_id, from_identity, to_id, to_identity, cost, graph_id) values ($1, $2, $3, $4, $5, $6, $7) returning id", e.Identity, fromNodeId, fromNodeIdentity, toNodeId, toNodeIdentity, e.Cost, g.Id).Scan(&g.Edges[i].Id)
-			if err != nil {
-				return err
+			args = append(args, e.Identity, fromId, e.FromIdentity, toId, e.ToIdentity, e.Cost, g.Id)
+		}
+		query := "insert into edge (identity, from_id, from_identity, to_id, to_identity, cost, graph_id) values " + valuesList(len(batch), 7) + " returning id, identity"
+		if err := scanIds(tx, edgeIds, query, args...); err != nil {
+			return s.dialect.constraintError(err)
+		}
+	}
+	for i, e := range g.Edges {
+		g.Edges[i].Id = edgeIds[e.Identity]
+		g.Edges[i].FromId = nodeIds[e.FromIdentity]
+		g.Edges[i].ToId = nodeIds[e.ToIdentity]
+	}
+
+	return tx.Commit()
+}
+
+// placeholders builds the list "($3, $4, $5)" of n parameters starting at $start.
+func placeholders(start int, n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = "$" + strconv.Itoa(start+i)
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// intArgs converts ids into query arguments.
+func intArgs(ids []int) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// valuesList builds the placeholder list "($1, $2), ($3, $4)" for a multi-row insert.
+func valuesList(rows int, columns int) string {
+	var b strings.Builder
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for c := 0; c < columns; c++ {
+			if c > 0 {
+				b.WriteString(", ")
 			}
+			b.WriteString("$" + strconv.Itoa(r*columns+c+1))
 		}
+		b.WriteString(")")
 	}
-	return nil
+	return b.String()
 }
 
-func (g *Graph) Get() error {
-	err := g.Db.QueryRow("select id, identity, name from graph where id = $1", g.Id).Scan(&g.Id, &g.Identity, &g.Name)
+// scanIds runs an insert returning (id, identity) rows and records them in ids.
+func scanIds(tx *sql.Tx, ids map[string]int, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
 	if err != nil {
 		return err
 	}
-	rows, err := g.Db.Query("select id, identity, name from node where graph_id = $1", g.Id)
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var identity string
+		if err := rows.Scan(&id, &identity); err != nil {
+			return err
+		}
+		ids[identity] = id
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) Get(id int) (*Graph, error) {
+	g := &Graph{}
+	err := s.Db.QueryRow("select id, identity, name, revision, created_at from graph where id = $1", id).Scan(&g.Id, &g.Identity, &g.Name, &g.Revision, &g.CreatedAt)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	rows, err := s.Db.Query("select n.id, n.identity, n.name from "+nodeRows+" order by n.id", g.Identity, g.Revision)
+	if err != nil {
+		return nil, err
 	}
 	for rows.Next() {
 		n := Node{}
 		err = rows.Scan(&n.Id, &n.Identity, &n.Name)
 		if err != nil {
-			return err
+			rows.Close()
+			return nil, err
 		}
 		g.Nodes = append(g.Nodes, n)
 	}
 	rows.Close()
 
-	r, err := g.Db.Query("select id, identity, from_id, from_identity, to_id, to_identity, cost from edge where graph_id = $1", g.Id)
+	r, err := s.Db.Query("select "+edgeColumns+" from "+edgeRows+" order by e.id", g.Identity, g.Revision)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for r.Next() {
 		e := Edge{}
-		err = r.Scan(&e.Id, &e.Identity, &e.FromId, &e.FromIdentity, &e.ToId, &e.ToIdentity, &e.Cost)
+		err = scanEdge(r, &e)
 		if err != nil {
-			return err
+			r.Close()
+			return nil, err
 		}
 		g.Edges = append(g.Edges, e)
 	}
 	r.Close()
-	return nil
+	return g, nil
 }
 
-func (g *Graph) FindCycles() ([][]string, error) {
-	result := [][]string{}
-	rows, err := g.Db.Query(`WITH RECURSIVE cte AS (
-    -- Anchor member: start from each edge
-		SELECT 
-			from_identity, 
-			to_identity, 
-			',' || from_identity || ',' || to_identity || ',' AS nodes,  -- Concatenate with commas
-			1 AS lev, 
-			CASE WHEN from_identity = to_identity THEN 1 ELSE 0 END AS has_cycle
-		FROM edge e where from_identity <> to_identity AND graph_id = $1
-		
-		UNION ALL
-		
-		-- Recursive member: find the next edge
-		SELECT 
-			cte.from_identity, 
-			e.to_identity,
-			cte.nodes || e.to_identity || ',' AS nodes,  -- Append the target to the path
-			lev + 1,
-			CASE WHEN cte.nodes LIKE '%' || e.to_identity || '%' THEN 1 ELSE 0 END AS has_cycle
-		FROM cte 
-		JOIN edge e ON e.from_identity = cte.to_identity
-		WHERE cte.has_cycle = 0 AND e.from_identity <> e.to_identity AND graph_id = $1
-	)
-	SELECT *
-	FROM cte
-	WHERE has_cycle = 1;`,
-		g.Id)
+// FindCycles loads graph id and searches it for cycles in process.
+func (s *sqlStore) FindCycles(id int, limit int) ([][]string, error) {
+	g, err := s.Get(id)
 	if err != nil {
 		return nil, err
 	}
-	for rows.Next() {
-		r := []string{}
-		err = rows.Scan(pq.Array(&r))
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, r)
+	return NewIndex(g).Cycles(limit), nil
+}
+
+// TopoSort loads graph id and sorts it in process.
+func (s *sqlStore) TopoSort(id int) (TopoOrder, error) {
+	g, err := s.Get(id)
+	if err != nil {
+		return TopoOrder{}, err
 	}
-	rows.Close()
+	return NewIndex(g).TopoSort(), nil
+}
 
-	return result, nil
+func (s *sqlStore) List() ([]Revision, error) {
+	rows, err := s.Db.Query("select " + revisionColumns + " from graph order by identity, revision")
+	if err != nil {
+		return nil, err
+	}
+	return scanRevisions(rows)
 }
@@ -1,19 +1,32 @@
 package model
 
 import (
+	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+// expectReplace registers the statements removing the nodes and edges of the
+// revisions before revision of identity, whose id is id.
+func expectReplace(mock sqlmock.Sqlmock, revision int, identity string, id int) {
+	for _, table := range []string{"node", "edge"} {
+		mock.ExpectExec("update "+table+" set removed_in = \\$1 where removed_in is null and graph_id in \\(select id from graph where identity = \\$2 and id <> \\$3\\)").
+			WithArgs(revision, identity, id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
 func TestGraph_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
 	graph := &Graph{
-		Db:       db,
 		Identity: "graph-1",
 		Name:     "Test Graph",
 		Nodes: []Node{
@@ -25,68 +38,131 @@ func TestGraph_Create(t *testing.T) {
 		},
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectQuery("insert into graph").
-		WithArgs(graph.Identity, graph.Name).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WithArgs(graph.Identity, graph.Name, graph.Hash(), graph.Identity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(1, 1, time.Now()))
+	expectReplace(mock, 1, "graph-1", 1)
 
-	for i, node := range graph.Nodes {
-		mock.ExpectQuery("insert into node").
-			WithArgs(node.Identity, node.Name, 1).
-			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 1))
-	}
-
-	mock.ExpectQuery("select id, identity from node where identity = \\$1 and graph_id = \\$2").
-		WithArgs("node-1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "node-1"))
-
-	mock.ExpectQuery("select id, identity from node where identity = \\$1 and graph_id = \\$2").
-		WithArgs("node-2", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(2, "node-2"))
+	mock.ExpectQuery("insert into node \\(identity, name, graph_id\\) values \\(\\$1, \\$2, \\$3\\), \\(\\$4, \\$5, \\$6\\) returning id, identity").
+		WithArgs("node-1", "Node 1", 1, "node-2", "Node 2", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "node-1").AddRow(2, "node-2"))
 
 	mock.ExpectQuery("insert into edge").
 		WithArgs("edge-1", 1, "node-1", 2, "node-2", 1.0, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "edge-1"))
+	mock.ExpectCommit()
 
-	err = graph.Create()
+	err = NewPostgresStore(db).Create(graph)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, graph.Id)
 	assert.Equal(t, 1, graph.Nodes[0].Id)
 	assert.Equal(t, 2, graph.Nodes[1].Id)
 	assert.Equal(t, 1, graph.Edges[0].Id)
+	assert.Equal(t, 1, graph.Edges[0].FromId)
+	assert.Equal(t, 2, graph.Edges[0].ToId)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestGraph_Get(t *testing.T) {
+func TestGraph_CreateRollsBackOnFailure(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
 	graph := &Graph{
-		Db: db,
-		Id: 1,
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes: []Node{
+			{Identity: "node-1", Name: "Node 1"},
+		},
 	}
 
-	mock.ExpectQuery("select id, identity, name from graph where id = \\$1").
-		WithArgs(graph.Id).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name"}).AddRow(1, "graph-1", "Test Graph"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph").
+		WithArgs(graph.Identity, graph.Name, graph.Hash(), graph.Identity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(1, 1, time.Now()))
+	expectReplace(mock, 1, "graph-1", 1)
+	mock.ExpectQuery("insert into node").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
 
-	mock.ExpectQuery("select id, identity, name from node where graph_id = \\$1").
-		WithArgs(graph.Id).
+	err = NewPostgresStore(db).Create(graph)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateRevisionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph .* where identity = \\$4").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "graph_revision_key"})
+	mock.ExpectRollback()
+
+	err = NewPostgresStore(db).Create(&Graph{Identity: "graph-1", Name: "Test Graph"})
+	assert.ErrorIs(t, err, ErrRevisionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateBatchesInserts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	graph := &Graph{Identity: "graph-1", Name: "Big Graph"}
+	for i := 0; i < insertBatchSize+1; i++ {
+		graph.Nodes = append(graph.Nodes, Node{Identity: strconv.Itoa(i), Name: strconv.Itoa(i)})
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(1, 1, time.Now()))
+	expectReplace(mock, 1, "graph-1", 1)
+	firstBatch := sqlmock.NewRows([]string{"id", "identity"})
+	for i := 0; i < insertBatchSize; i++ {
+		firstBatch.AddRow(i+1, strconv.Itoa(i))
+	}
+	mock.ExpectQuery("insert into node").WillReturnRows(firstBatch)
+	mock.ExpectQuery("insert into node \\(identity, name, graph_id\\) values \\(\\$1, \\$2, \\$3\\) returning").
+		WithArgs(strconv.Itoa(insertBatchSize), strconv.Itoa(insertBatchSize), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(insertBatchSize+1, strconv.Itoa(insertBatchSize)))
+	mock.ExpectCommit()
+
+	err = NewPostgresStore(db).Create(graph)
+	assert.NoError(t, err)
+	assert.Equal(t, insertBatchSize+1, graph.Nodes[insertBatchSize].Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select id, identity, name, revision, created_at from graph where id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name", "revision", "created_at"}).AddRow(1, "graph-1", "Test Graph", 2, time.Now()))
+
+	mock.ExpectQuery("select n.id, n.identity, n.name from node n join graph g on g.id = n.graph_id where g.identity = \\$1 and g.revision <= \\$2 and \\(n.removed_in is null or n.removed_in > \\$2\\) order by n.id").
+		WithArgs("graph-1", 2).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name"}).
 			AddRow(1, "node-1", "Node 1").
 			AddRow(2, "node-2", "Node 2"))
 
-	mock.ExpectQuery("select id, identity, from_id, from_identity, to_id, to_identity, cost from edge where graph_id = \\$1").
-		WithArgs(graph.Id).
+	mock.ExpectQuery("select e.id, e.identity, e.from_id, e.from_identity, e.to_id, e.to_identity, e.cost from edge e join graph g on g.id = e.graph_id where g.identity = \\$1 and g.revision <= \\$2 and \\(e.removed_in is null or e.removed_in > \\$2\\) order by e.id").
+		WithArgs("graph-1", 2).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "from_id", "from_identity", "to_id", "to_identity", "cost"}).
 			AddRow(1, "edge-1", 1, "node-1", 2, "node-2", 1.0))
 
-	err = graph.Get()
+	graph, err := NewPostgresStore(db).Get(1)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, graph.Id)
 	assert.Equal(t, "graph-1", graph.Identity)
 	assert.Equal(t, "Test Graph", graph.Name)
+	assert.Equal(t, 2, graph.Revision)
 	assert.Len(t, graph.Nodes, 2)
 	assert.Equal(t, 1, graph.Nodes[0].Id)
 	assert.Equal(t, "node-1", graph.Nodes[0].Identity)
@@ -105,3 +181,80 @@ func TestGraph_Get(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGraph_HashIgnoresOrder(t *testing.T) {
+	a := &Graph{
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes:    []Node{{Identity: "node-1", Name: "Node 1"}, {Identity: "node-2", Name: "Node 2"}},
+		Edges:    []Edge{{Identity: "edge-1", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 1.5}},
+	}
+	b := &Graph{
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes:    []Node{{Identity: "node-2", Name: "Node 2"}, {Identity: "node-1", Name: "Node 1"}},
+		Edges:    []Edge{{Identity: "edge-1", FromIdentity: "node-1", ToIdentity: "node-2", Cost: 1.5}},
+	}
+	assert.Equal(t, a.Hash(), b.Hash())
+
+	b.Edges[0].Cost = 2
+	assert.NotEqual(t, a.Hash(), b.Hash())
+}
+
+func TestGraph_ImportReusesExistingGraph(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	graph := &Graph{
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes:    []Node{{Identity: "node-1", Name: "Node 1"}},
+	}
+
+	mock.ExpectQuery("select id, revision, content_hash from graph where identity = \\$1 order by revision desc limit 1").
+		WithArgs(graph.Identity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "content_hash"}).AddRow(3, 2, graph.Hash()))
+	mock.ExpectExec("update graph set last_used_at = current_timestamp where id = \\$1").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	created, err := NewPostgresStore(db).Import(graph)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 3, graph.Id)
+	assert.Equal(t, 2, graph.Revision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_ImportCreatesChangedGraph(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	graph := &Graph{
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes:    []Node{{Identity: "node-1", Name: "Node 1"}},
+	}
+
+	mock.ExpectQuery("select id, revision, content_hash from graph where identity = \\$1 order by revision desc limit 1").
+		WithArgs(graph.Identity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "content_hash"}).AddRow(3, 2, "changed"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into graph").
+		WithArgs(graph.Identity, graph.Name, graph.Hash(), graph.Identity).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "created_at"}).AddRow(4, 3, time.Now()))
+	expectReplace(mock, 3, "graph-1", 4)
+	mock.ExpectQuery("insert into node").
+		WithArgs("node-1", "Node 1", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity"}).AddRow(1, "node-1"))
+	mock.ExpectCommit()
+
+	created, err := NewPostgresStore(db).Import(graph)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 4, graph.Id)
+	assert.Equal(t, 3, graph.Revision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
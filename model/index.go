@@ -0,0 +1,142 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Arc is an edge in an Index, seen from one of its ends. To is the other end.
+type Arc struct {
+	// Edge is the identity of the edge.
+	Edge string
+	To   string
+	Cost float64
+}
+
+// Index is an in-memory, read-only view of one stored graph prepared for path
+// queries. It must not be modified once built, so it can be shared between
+// concurrent requests.
+type Index struct {
+	Graph *Graph
+	// Out maps every node identity to its outgoing arcs. Self-loops are left out,
+	// they never appear on a simple path.
+	Out map[string][]Arc
+	// In maps every node identity to its incoming arcs, whose To is the node the
+	// edge comes from. Self-loops are left out as well.
+	In map[string][]Arc
+	// Nodes holds every node identity of the graph.
+	Nodes map[string]bool
+	// SelfLoops holds the identity of every node with an edge to itself.
+	SelfLoops map[string]bool
+	// Acyclic is set when the graph has no cycles, self-loops included.
+	Acyclic bool
+	// Version fingerprints Out with its arcs in order. Indexes with the same
+	// Version enumerate the same paths in the same order.
+	Version string
+}
+
+// NewIndex builds the index of g. g must not be modified afterwards.
+func NewIndex(g *Graph) *Index {
+	ix := &Index{
+		Graph:     g,
+		Out:       make(map[string][]Arc, len(g.Nodes)),
+		In:        make(map[string][]Arc, len(g.Nodes)),
+		Nodes:     make(map[string]bool, len(g.Nodes)),
+		SelfLoops: map[string]bool{},
+	}
+	for _, n := range g.Nodes {
+		ix.Nodes[n.Identity] = true
+	}
+	for _, e := range g.Edges {
+		if e.FromIdentity == e.ToIdentity {
+			ix.SelfLoops[e.FromIdentity] = true
+			continue
+		}
+		ix.Out[e.FromIdentity] = append(ix.Out[e.FromIdentity], Arc{Edge: e.Identity, To: e.ToIdentity, Cost: e.Cost})
+		ix.In[e.ToIdentity] = append(ix.In[e.ToIdentity], Arc{Edge: e.Identity, To: e.FromIdentity, Cost: e.Cost})
+	}
+	ix.Acyclic = len(ix.SelfLoops) == 0 && acyclic(ix.Out)
+	ix.Version = version(ix.Out)
+	return ix
+}
+
+// version hashes out with the nodes sorted and the arcs of every node in order.
+func version(out map[string][]Arc) string {
+	nodes := make([]string, 0, len(out))
+	for n := range out {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	h := sha256.New()
+	for _, n := range nodes {
+		fmt.Fprintf(h, "%q", n)
+		for _, a := range out[n] {
+			fmt.Fprintf(h, " %q %q %s", a.Edge, a.To, strconv.FormatFloat(a.Cost, 'g', -1, 64))
+		}
+		fmt.Fprintln(h)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// acyclic reports whether out has no cycle. It peels off nodes without incoming
+// arcs like Kahn's algorithm; only a cycle keeps some arcs from being removed.
+func acyclic(out map[string][]Arc) bool {
+	indegree := map[string]int{}
+	arcs := 0
+	for _, as := range out {
+		for _, a := range as {
+			indegree[a.To]++
+			arcs++
+		}
+	}
+	free := []string{}
+	for n := range out {
+		if indegree[n] == 0 {
+			free = append(free, n)
+		}
+	}
+	for len(free) > 0 {
+		n := free[len(free)-1]
+		free = free[:len(free)-1]
+		for _, a := range out[n] {
+			arcs--
+			if indegree[a.To]--; indegree[a.To] == 0 {
+				free = append(free, a.To)
+			}
+		}
+	}
+	return arcs == 0
+}
+
+// Cycles returns the elementary cycles of the graph, at most limit of them when
+// limit is positive. Self-loops come first, each as the cycle of its single node
+// and ordered by identity. See elementaryCycles for the order of the nodes of the
+// other cycles.
+func (ix *Index) Cycles(limit int) [][]string {
+	cycles := [][]string{}
+	for _, n := range ix.selfLoops() {
+		if limit > 0 && len(cycles) == limit {
+			return cycles
+		}
+		cycles = append(cycles, []string{n})
+	}
+	if limit > 0 {
+		if limit -= len(cycles); limit == 0 {
+			return cycles
+		}
+	}
+	return append(cycles, elementaryCycles(ix.Out, limit)...)
+}
+
+// selfLoops returns the nodes with an edge to themselves ordered by identity.
+func (ix *Index) selfLoops() []string {
+	loops := make([]string, 0, len(ix.SelfLoops))
+	for n := range ix.SelfLoops {
+		loops = append(loops, n)
+	}
+	sort.Strings(loops)
+	return loops
+}
@@ -0,0 +1,511 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a GraphStore keeping every graph in process memory. It follows
+// the rules of the SQL stores and is meant for tests and throwaway servers.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	graphs   map[int]*memoryGraph
+	lastId   int
+	lastNode int
+	lastEdge int
+}
+
+type memoryGraph struct {
+	graph      Graph
+	lastUsedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{graphs: make(map[int]*memoryGraph)}
+}
+
+// latest returns the newest revision stored under identity, or nil.
+func (s *MemoryStore) latest(identity string) *memoryGraph {
+	var latest *memoryGraph
+	for _, m := range s.graphs {
+		if m.graph.Identity == identity && (latest == nil || m.graph.Revision > latest.graph.Revision) {
+			latest = m
+		}
+	}
+	return latest
+}
+
+func (s *MemoryStore) Import(g *Graph) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := g.Hash()
+	if latest := s.latest(g.Identity); latest != nil && latest.graph.ContentHash == hash {
+		g.Id, g.Revision, g.ContentHash = latest.graph.Id, latest.graph.Revision, hash
+		latest.lastUsedAt = time.Now()
+		return false, nil
+	}
+	return true, s.create(g)
+}
+
+func (s *MemoryStore) Create(g *Graph) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(g)
+}
+
+func (s *MemoryStore) create(g *Graph) error {
+	nodeIds := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		if _, ok := nodeIds[n.Identity]; ok {
+			return ErrDuplicateNode
+		}
+		nodeIds[n.Identity] = 0
+	}
+	edgeIds := make(map[string]bool, len(g.Edges))
+	ends := make(map[[2]string]bool, len(g.Edges))
+	for _, e := range g.Edges {
+		for _, end := range []string{e.FromIdentity, e.ToIdentity} {
+			if _, ok := nodeIds[end]; !ok {
+				return fmt.Errorf("edge %q references unknown node %q", e.Identity, end)
+			}
+		}
+		if edgeIds[e.Identity] {
+			return ErrDuplicateEdge
+		}
+		if ends[[2]string{e.FromIdentity, e.ToIdentity}] {
+			return ErrDuplicateEdgeNodes
+		}
+		edgeIds[e.Identity] = true
+		ends[[2]string{e.FromIdentity, e.ToIdentity}] = true
+	}
+
+	s.lastId++
+	g.Id = s.lastId
+	g.Revision = 1
+	if latest := s.latest(g.Identity); latest != nil {
+		g.Revision = latest.graph.Revision + 1
+	}
+	g.ContentHash = g.Hash()
+	g.CreatedAt = time.Now()
+	for i := range g.Nodes {
+		s.lastNode++
+		g.Nodes[i].Id = s.lastNode
+		nodeIds[g.Nodes[i].Identity] = s.lastNode
+	}
+	for i := range g.Edges {
+		s.lastEdge++
+		g.Edges[i].Id = s.lastEdge
+		g.Edges[i].FromId = nodeIds[g.Edges[i].FromIdentity]
+		g.Edges[i].ToId = nodeIds[g.Edges[i].ToIdentity]
+	}
+
+	s.graphs[g.Id] = &memoryGraph{graph: copyGraph(g), lastUsedAt: g.CreatedAt}
+	return nil
+}
+
+// copyGraph returns g with its own node and edge slices.
+func copyGraph(g *Graph) Graph {
+	c := *g
+	c.Nodes = append([]Node(nil), g.Nodes...)
+	c.Edges = append([]Edge(nil), g.Edges...)
+	return c
+}
+
+func (s *MemoryStore) Get(id int) (*Graph, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	g := copyGraph(&m.graph)
+	g.ContentHash = ""
+	return &g, nil
+}
+
+func revisionOf(g *Graph) Revision {
+	return Revision{Id: g.Id, Identity: g.Identity, Revision: g.Revision, Name: g.Name, ContentHash: g.ContentHash, CreatedAt: g.CreatedAt}
+}
+
+func (s *MemoryStore) List() ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Revision, 0, len(s.graphs))
+	for _, m := range s.graphs {
+		result = append(result, revisionOf(&m.graph))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Identity != result[j].Identity {
+			return result[i].Identity < result[j].Identity
+		}
+		return result[i].Revision < result[j].Revision
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) Delete(id int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.graphs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return s.deleteIdentity(m.graph.Identity), nil
+}
+
+// deleteIdentity removes every revision of identity and returns their ids in order.
+func (s *MemoryStore) deleteIdentity(identity string) []int {
+	deleted := []int{}
+	for id, m := range s.graphs {
+		if m.graph.Identity == identity {
+			delete(s.graphs, id)
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Ints(deleted)
+	return deleted
+}
+
+func (s *MemoryStore) Revisions(id int) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	result := []Revision{}
+	for _, other := range s.graphs {
+		if other.graph.Identity == m.graph.Identity {
+			result = append(result, revisionOf(&other.graph))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Revision < result[j].Revision })
+	return result, nil
+}
+
+func (s *MemoryStore) ResolveRevision(id int, revision int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	for _, other := range s.graphs {
+		if other.graph.Identity == m.graph.Identity && other.graph.Revision == revision {
+			return other.graph.Id, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *MemoryStore) LatestRevision(id int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return s.latest(m.graph.Identity).graph.Id, nil
+}
+
+func (s *MemoryStore) MarkUsed(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.graphs[id]; ok {
+		m.lastUsedAt = time.Now()
+	}
+	return nil
+}
+
+func (s *MemoryStore) PurgeStale(before time.Time, keep []int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a graph is stale when none of its revisions was used since before
+	stale := map[string]bool{}
+	for _, m := range s.graphs {
+		if _, seen := stale[m.graph.Identity]; !seen {
+			stale[m.graph.Identity] = true
+		}
+		if !m.lastUsedAt.Before(before) {
+			stale[m.graph.Identity] = false
+		}
+	}
+	for _, id := range keep {
+		if m, ok := s.graphs[id]; ok {
+			stale[m.graph.Identity] = false
+		}
+	}
+	deleted := []int{}
+	for identity, purge := range stale {
+		if purge {
+			deleted = append(deleted, s.deleteIdentity(identity)...)
+		}
+	}
+	sort.Ints(deleted)
+	return deleted, nil
+}
+
+func (s *MemoryStore) FindCycles(id int, limit int) ([][]string, error) {
+	g, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(g).Cycles(limit), nil
+}
+
+func (s *MemoryStore) TopoSort(id int) (TopoOrder, error) {
+	g, err := s.Get(id)
+	if err != nil {
+		return TopoOrder{}, err
+	}
+	return NewIndex(g).TopoSort(), nil
+}
+
+// branch copies graph graphId into the next revision of its identity, which only
+// the latest revision may have. The copy keeps the node and edge ids, as the SQL
+// stores share unchanged rows between revisions, and is only stored by commit,
+// so a mutation that fails leaves nothing behind.
+func (s *MemoryStore) branch(graphId int) (*memoryGraph, error) {
+	m, ok := s.graphs[graphId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	latest := s.latest(m.graph.Identity)
+	if latest != m {
+		return nil, ErrNotLatestRevision
+	}
+	g := copyGraph(&m.graph)
+	s.lastId++
+	g.Id = s.lastId
+	g.Revision = latest.graph.Revision + 1
+	g.ContentHash = ""
+	g.CreatedAt = time.Now()
+	return &memoryGraph{graph: g, lastUsedAt: g.CreatedAt}, nil
+}
+
+// commit stores a revision made by branch and returns its id.
+func (s *MemoryStore) commit(m *memoryGraph) int {
+	s.graphs[m.graph.Id] = m
+	return m.graph.Id
+}
+
+func (s *MemoryStore) ListNodes(graphId int) ([]Node, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[graphId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	result := append([]Node{}, m.graph.Nodes...)
+	sort.Slice(result, func(i, j int) bool { return result[i].Identity < result[j].Identity })
+	return result, nil
+}
+
+func (s *MemoryStore) GetNode(graphId int, identity string) (Node, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if m, ok := s.graphs[graphId]; ok {
+		for _, n := range m.graph.Nodes {
+			if n.Identity == identity {
+				return n, nil
+			}
+		}
+	}
+	return Node{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) CreateNode(graphId int, n *Node) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	for _, other := range m.graph.Nodes {
+		if other.Identity == n.Identity {
+			return 0, ErrDuplicateNode
+		}
+	}
+	s.lastNode++
+	n.Id = s.lastNode
+	m.graph.Nodes = append(m.graph.Nodes, *n)
+	return s.commit(m), nil
+}
+
+func (s *MemoryStore) UpdateNode(graphId int, n *Node) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	for i, other := range m.graph.Nodes {
+		if other.Identity != n.Identity {
+			continue
+		}
+		// a renamed node is a new row in the SQL stores, and so are its edges
+		s.lastNode++
+		n.Id = s.lastNode
+		m.graph.Nodes[i] = *n
+		for j, e := range m.graph.Edges {
+			if e.FromId != other.Id && e.ToId != other.Id {
+				continue
+			}
+			s.lastEdge++
+			m.graph.Edges[j].Id = s.lastEdge
+			if e.FromId == other.Id {
+				m.graph.Edges[j].FromId = n.Id
+			}
+			if e.ToId == other.Id {
+				m.graph.Edges[j].ToId = n.Id
+			}
+		}
+		return s.commit(m), nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *MemoryStore) DeleteNode(graphId int, identity string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	for i, n := range m.graph.Nodes {
+		if n.Identity != identity {
+			continue
+		}
+		m.graph.Nodes = append(m.graph.Nodes[:i:i], m.graph.Nodes[i+1:]...)
+		edges := m.graph.Edges[:0:0]
+		for _, e := range m.graph.Edges {
+			if e.FromId != n.Id && e.ToId != n.Id {
+				edges = append(edges, e)
+			}
+		}
+		m.graph.Edges = edges
+		return s.commit(m), nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *MemoryStore) ListEdges(graphId int) ([]Edge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.graphs[graphId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	result := append([]Edge{}, m.graph.Edges...)
+	sort.Slice(result, func(i, j int) bool { return result[i].Identity < result[j].Identity })
+	return result, nil
+}
+
+func (s *MemoryStore) GetEdge(graphId int, identity string) (Edge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if m, ok := s.graphs[graphId]; ok {
+		for _, e := range m.graph.Edges {
+			if e.Identity == identity {
+				return e, nil
+			}
+		}
+	}
+	return Edge{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) CreateEdge(graphId int, e *Edge) (int, error) {
+	if e.Cost < 0 {
+		return 0, ErrNegativeCost
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	e.FromId, e.ToId = 0, 0
+	for _, n := range m.graph.Nodes {
+		if n.Identity == e.FromIdentity {
+			e.FromId = n.Id
+		}
+		if n.Identity == e.ToIdentity {
+			e.ToId = n.Id
+		}
+	}
+	if e.FromId == 0 || e.ToId == 0 {
+		return 0, ErrUnknownNode
+	}
+	for _, other := range m.graph.Edges {
+		if other.Identity == e.Identity {
+			return 0, ErrDuplicateEdge
+		}
+		if other.FromId == e.FromId && other.ToId == e.ToId {
+			return 0, ErrDuplicateEdgeNodes
+		}
+	}
+	s.lastEdge++
+	e.Id = s.lastEdge
+	m.graph.Edges = append(m.graph.Edges, *e)
+	return s.commit(m), nil
+}
+
+func (s *MemoryStore) UpdateEdge(graphId int, e *Edge) (int, error) {
+	if e.Cost < 0 {
+		return 0, ErrNegativeCost
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	for i, other := range m.graph.Edges {
+		if other.Identity == e.Identity {
+			s.lastEdge++
+			m.graph.Edges[i].Id = s.lastEdge
+			m.graph.Edges[i].Cost = e.Cost
+			*e = m.graph.Edges[i]
+			return s.commit(m), nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *MemoryStore) DeleteEdge(graphId int, identity string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.branch(graphId)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range m.graph.Edges {
+		if e.Identity == identity {
+			m.graph.Edges = append(m.graph.Edges[:i:i], m.graph.Edges[i+1:]...)
+			return s.commit(m), nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
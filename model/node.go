@@ -1,7 +1,194 @@
 package model
 
+import (
+	"database/sql"
+	"errors"
+)
+
 type Node struct {
 	Id       int
 	Identity string `xml:"id"`
 	Name     string `xml:"name"`
 }
+
+// A revision does not copy the nodes and edges of the previous one. A node or
+// edge row belongs to the revision that added it, graph_id, and stays part of
+// every later revision of the same identity until removed_in, the first revision
+// it is no longer part of. A mutation only writes the rows it changes.
+
+// nodeRows selects the nodes of revision $2 of graph identity $1.
+const nodeRows = "node n join graph g on g.id = n.graph_id where g.identity = $1 and g.revision <= $2 and (n.removed_in is null or n.removed_in > $2)"
+
+// queryRower is implemented by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// graphRevision returns the identity and revision number of graph id, or
+// sql.ErrNoRows when the graph is not stored.
+func graphRevision(q queryRower, id int) (identity string, revision int, err error) {
+	err = q.QueryRow("select identity, revision from graph where id = $1", id).Scan(&identity, &revision)
+	return identity, revision, err
+}
+
+// nextRevision is the revision saved by a mutation.
+type nextRevision struct {
+	id       int
+	identity string
+	revision int
+}
+
+// next saves the revision following graph graphId inside tx. Only the latest
+// revision of a graph can be changed: graphId must be it, otherwise next returns
+// ErrNotLatestRevision. The new revision has no content hash, so the next import
+// creates a new revision. When another change saved the same revision first,
+// next returns ErrRevisionConflict.
+func (s *sqlStore) next(tx *sql.Tx, graphId int) (nextRevision, error) {
+	r := nextRevision{}
+	var revision, latest int
+	err := tx.QueryRow("select identity, revision, (select max(revision) from graph l where l.identity = g.identity) from graph g where id = $1", graphId).
+		Scan(&r.identity, &revision, &latest)
+	if err != nil {
+		return r, err
+	}
+	if revision != latest {
+		return r, ErrNotLatestRevision
+	}
+	err = tx.QueryRow("insert into graph (identity, name, revision) select identity, name, revision + 1 from graph where id = $1 returning id, revision", graphId).
+		Scan(&r.id, &r.revision)
+	if err != nil {
+		return r, s.dialect.constraintError(err)
+	}
+	return r, nil
+}
+
+// liveNode returns the id of the node with the given identity in the latest
+// revision of graph identity graph, or sql.ErrNoRows.
+func liveNode(tx *sql.Tx, graph string, identity string) (int, error) {
+	var id int
+	err := tx.QueryRow("select n.id from node n join graph g on g.id = n.graph_id where g.identity = $1 and n.removed_in is null and n.identity = $2", graph, identity).Scan(&id)
+	return id, err
+}
+
+// ListNodes returns the nodes of graph graphId ordered by identity.
+func (s *sqlStore) ListNodes(graphId int) ([]Node, error) {
+	graph, revision, err := graphRevision(s.Db, graphId)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Db.Query("select n.id, n.identity, n.name from "+nodeRows+" order by n.identity", graph, revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []Node{}
+	for rows.Next() {
+		n := Node{}
+		if err := rows.Scan(&n.Id, &n.Identity, &n.Name); err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+// GetNode returns the node with the given identity, or sql.ErrNoRows.
+func (s *sqlStore) GetNode(graphId int, identity string) (Node, error) {
+	n := Node{}
+	graph, revision, err := graphRevision(s.Db, graphId)
+	if err != nil {
+		return n, err
+	}
+	err = s.Db.QueryRow("select n.id, n.identity, n.name from "+nodeRows+" and n.identity = $3", graph, revision, identity).Scan(&n.Id, &n.Identity, &n.Name)
+	return n, err
+}
+
+// CreateNode adds n to a new revision of graph graphId, sets n.Id and returns
+// the id of the revision.
+func (s *sqlStore) CreateNode(graphId int, n *Node) (int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := liveNode(tx, r.identity, n.Identity); err == nil {
+		return 0, ErrDuplicateNode
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	err = tx.QueryRow("insert into node (identity, name, graph_id) values ($1, $2, $3) returning id", n.Identity, n.Name, r.id).Scan(&n.Id)
+	if err != nil {
+		return 0, s.dialect.constraintError(err)
+	}
+	return r.id, tx.Commit()
+}
+
+// UpdateNode renames the node with identity n.Identity in a new revision of graph
+// graphId, sets n.Id and returns the id of the revision. The renamed node is a
+// new row, so the edges starting or ending at it are added again pointing at it.
+func (s *sqlStore) UpdateNode(graphId int, n *Node) (int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	old, err := liveNode(tx, r.identity, n.Identity)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update node set removed_in = $1 where id = $2", r.revision, old); err != nil {
+		return 0, err
+	}
+	err = tx.QueryRow("insert into node (identity, name, graph_id) values ($1, $2, $3) returning id", n.Identity, n.Name, r.id).Scan(&n.Id)
+	if err != nil {
+		return 0, s.dialect.constraintError(err)
+	}
+	_, err = tx.Exec(`insert into edge (identity, from_id, from_identity, to_id, to_identity, cost, graph_id)
+		select identity, case when from_id = $1 then cast($2 as integer) else from_id end, from_identity,
+			case when to_id = $1 then cast($2 as integer) else to_id end, to_identity, cost, cast($3 as integer)
+		from edge where removed_in is null and (from_id = $1 or to_id = $1) order by id`, old, n.Id, r.id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update edge set removed_in = $1 where removed_in is null and (from_id = $2 or to_id = $2)", r.revision, old); err != nil {
+		return 0, err
+	}
+	return r.id, tx.Commit()
+}
+
+// DeleteNode removes the node with the given identity together with every edge
+// starting or ending at it from a new revision of graph graphId, and returns the
+// id of the revision.
+func (s *sqlStore) DeleteNode(graphId int, identity string) (int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.next(tx, graphId)
+	if err != nil {
+		return 0, err
+	}
+	id, err := liveNode(tx, r.identity, identity)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update edge set removed_in = $1 where removed_in is null and (from_id = $2 or to_id = $2)", r.revision, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update node set removed_in = $1 where id = $2", r.revision, id); err != nil {
+		return 0, err
+	}
+	return r.id, tx.Commit()
+}
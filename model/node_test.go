@@ -0,0 +1,215 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectNext registers the statements saving revision 2 with id to after
+// revision 1 of graph-1, whose id is from.
+func expectNext(mock sqlmock.Sqlmock, from int, to int) {
+	mock.ExpectQuery("select identity, revision, \\(select max\\(revision\\) from graph l where l.identity = g.identity\\) from graph g where id = \\$1").
+		WithArgs(from).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision", "max"}).AddRow("graph-1", 1, 1))
+	mock.ExpectQuery("insert into graph \\(identity, name, revision\\) select identity, name, revision \\+ 1 from graph where id = \\$1 returning id, revision").
+		WithArgs(from).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision"}).AddRow(to, 2))
+}
+
+// expectLiveNode registers the lookup of node identity in the latest revision of
+// graph-1, returning id or no row when id is 0.
+func expectLiveNode(mock sqlmock.Sqlmock, identity string, id int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	if id != 0 {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("select n.id from node n join graph g on g.id = n.graph_id where g.identity = \\$1 and n.removed_in is null and n.identity = \\$2").
+		WithArgs("graph-1", identity).
+		WillReturnRows(rows)
+}
+
+func TestGraph_ListNodes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select identity, revision from graph where id = \\$1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision"}).AddRow("graph-1", 2))
+	mock.ExpectQuery("select n.id, n.identity, n.name from node n join graph g on g.id = n.graph_id where g.identity = \\$1 and g.revision <= \\$2 and \\(n.removed_in is null or n.removed_in > \\$2\\) order by n.identity").
+		WithArgs("graph-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "name"}).AddRow(1, "node-1", "Node 1").AddRow(3, "node-3", "Node 3"))
+
+	nodes, err := NewPostgresStore(db).ListNodes(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{{Id: 1, Identity: "node-1", Name: "Node 1"}, {Id: 3, Identity: "node-3", Name: "Node 3"}}, nodes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-3", 0)
+	mock.ExpectQuery("insert into node \\(identity, name, graph_id\\)").
+		WithArgs("node-3", "Node 3", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	store := NewPostgresStore(db)
+	node := Node{Identity: "node-3", Name: "Node 3"}
+	revisionId, err := store.CreateNode(1, &node)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revisionId)
+	assert.Equal(t, 3, node.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNodeDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 1)
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateNode(1, &Node{Identity: "node-1", Name: "Node 1"})
+	assert.ErrorIs(t, err, ErrDuplicateNode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNodeConstraint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 0)
+	mock.ExpectQuery("insert into node").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "node_key"})
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateNode(1, &Node{Identity: "node-1", Name: "Node 1"})
+	assert.ErrorIs(t, err, ErrDuplicateNode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNodeUnknownGraph(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select identity, revision, .* from graph g where id = \\$1").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision", "max"}))
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateNode(5, &Node{Identity: "node-1", Name: "Node 1"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNodeOlderRevision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select identity, revision, .* from graph g where id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision", "max"}).AddRow("graph-1", 1, 2))
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateNode(1, &Node{Identity: "node-3", Name: "Node 3"})
+	assert.ErrorIs(t, err, ErrNotLatestRevision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_CreateNodeRevisionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select identity, revision, .* from graph g where id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"identity", "revision", "max"}).AddRow("graph-1", 1, 1))
+	mock.ExpectQuery("insert into graph").
+		WithArgs(1).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "graph_revision_key"})
+	mock.ExpectRollback()
+
+	store := NewPostgresStore(db)
+	_, err = store.CreateNode(1, &Node{Identity: "node-3", Name: "Node 3"})
+	assert.ErrorIs(t, err, ErrRevisionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_UpdateNodeMovesEdges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 4)
+	mock.ExpectExec("update node set removed_in = \\$1 where id = \\$2").
+		WithArgs(2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("insert into node \\(identity, name, graph_id\\)").
+		WithArgs("node-1", "Renamed", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("insert into edge .* from edge where removed_in is null and \\(from_id = \\$1 or to_id = \\$1\\)").
+		WithArgs(4, 9, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("update edge set removed_in = \\$1 where removed_in is null and \\(from_id = \\$2 or to_id = \\$2\\)").
+		WithArgs(2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	store := NewPostgresStore(db)
+	node := Node{Identity: "node-1", Name: "Renamed"}
+	revisionId, err := store.UpdateNode(1, &node)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revisionId)
+	assert.Equal(t, 9, node.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_DeleteNodeRemovesEdges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectNext(mock, 1, 2)
+	expectLiveNode(mock, "node-1", 4)
+	mock.ExpectExec("update edge set removed_in = \\$1 where removed_in is null and \\(from_id = \\$2 or to_id = \\$2\\)").
+		WithArgs(2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("update node set removed_in = \\$1 where id = \\$2").
+		WithArgs(2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := NewPostgresStore(db)
+	revisionId, err := store.DeleteNode(1, "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revisionId)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore is the GraphStore backed by PostgreSQL. The schema is created
+// by the migrations directory.
+type PostgresStore struct {
+	*sqlStore
+}
+
+// NewPostgresStore returns a store using db, which must be opened with the
+// postgres driver.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{&sqlStore{Db: db, dialect: dialect{
+		forUpdate:       " for update",
+		constraintError: postgresConstraintError,
+		timeArg:         func(t time.Time) interface{} { return t },
+	}}}
+}
+
+// postgresConstraintError translates unique constraint violations into model errors.
+func postgresConstraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+	switch pqErr.Constraint {
+	case "node_key":
+		return ErrDuplicateNode
+	case "edge_key":
+		return ErrDuplicateEdge
+	case "edge_key2":
+		return ErrDuplicateEdgeNodes
+	case "graph_revision_key":
+		return ErrRevisionConflict
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Delete removes every revision of the graph id belongs to, with their nodes and
+// edges, in one transaction. It returns sql.ErrNoRows when the graph does not exist.
+func (s *sqlStore) Delete(id int) ([]int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := selectIds(tx, "select id from graph where identity = (select identity from graph where id = $1)"+s.dialect.forUpdate, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	deleted, err := deleteGraphs(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// MarkUsed records that graph id was just queried, which keeps it from being
+// purged by the retention policy.
+func (s *sqlStore) MarkUsed(id int) error {
+	_, err := s.Db.Exec("update graph set last_used_at = current_timestamp where id = $1", id)
+	return err
+}
+
+// PurgeStale deletes every revision of the graphs none of whose revisions was
+// queried or updated since before, except the graphs with a revision in keep, and
+// returns the ids of the deleted revisions.
+func (s *sqlStore) PurgeStale(before time.Time, keep []int) ([]int, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := "select id from graph where identity in (select identity from graph group by identity having max(last_used_at) < $1)"
+	if len(keep) > 0 {
+		query += " and identity not in (select identity from graph where id in " + placeholders(2, len(keep)) + ")"
+	}
+	ids, err := selectIds(tx, query+s.dialect.forUpdate, append([]interface{}{s.dialect.timeArg(before)}, intArgs(keep)...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	deleted, err := deleteGraphs(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// selectIds runs a query selecting graph ids inside tx.
+func selectIds(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// deleteGraphs removes the edges, nodes and graph rows of ids inside tx and
+// returns the ids of the graphs that existed.
+func deleteGraphs(tx *sql.Tx, ids []int) ([]int, error) {
+	in := placeholders(1, len(ids))
+	args := intArgs(ids)
+	if _, err := tx.Exec("delete from edge where graph_id in "+in, args...); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("delete from node where graph_id in "+in, args...); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query("delete from graph where id in "+in+" returning id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deleted := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, id)
+	}
+	return deleted, rows.Err()
+}
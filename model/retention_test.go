@@ -0,0 +1,99 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeStale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	before := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id from graph where identity in \\(select identity from graph group by identity having max\\(last_used_at\\) < \\$1\\) "+
+		"and identity not in \\(select identity from graph where id in \\(\\$2\\)\\) for update").
+		WithArgs(before, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectExec("delete from edge where graph_id in \\(\\$1, \\$2\\)").
+		WithArgs(2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("delete from node where graph_id in \\(\\$1, \\$2\\)").
+		WithArgs(2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectQuery("delete from graph where id in \\(\\$1, \\$2\\) returning id").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectCommit()
+
+	deleted, err := NewPostgresStore(db).PurgeStale(before, []int{1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeStale_NothingToDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id from graph where identity in").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	deleted, err := NewPostgresStore(db).PurgeStale(time.Now(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete_RemovesEveryRevision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select id from graph where identity = \\(select identity from graph where id = \\$1\\) for update").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectExec("delete from edge where graph_id in \\(\\$1, \\$2\\)").
+		WithArgs(2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("delete from node where graph_id in \\(\\$1, \\$2\\)").
+		WithArgs(2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectQuery("delete from graph where id in \\(\\$1, \\$2\\) returning id").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectCommit()
+
+	deleted, err := NewPostgresStore(db).Delete(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMemoryStore_PurgeStaleKeepsGraphsInUse(t *testing.T) {
+	store := NewMemoryStore()
+	first, second := testGraph(), testGraph()
+	second.Name = "Changed"
+	assert.NoError(t, store.Create(first))
+	assert.NoError(t, store.Create(second))
+	old := time.Now().Add(-time.Hour)
+
+	// only the older revision is used, so the latest one is not purged alone
+	store.graphs[second.Id].lastUsedAt = old
+	deleted, err := store.PurgeStale(time.Now().Add(-time.Minute), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	store.graphs[first.Id].lastUsedAt = old
+	deleted, err = store.PurgeStale(time.Now().Add(-time.Minute), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{first.Id, second.Id}, deleted)
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Revision describes one stored version of a graph. Every revision is its own
+// graph row; revisions of the same graph share the graph identity.
+type Revision struct {
+	Id          int       `json:"id"`
+	Identity    string    `json:"identity"`
+	Revision    int       `json:"revision"`
+	Name        string    `json:"name"`
+	ContentHash string    `json:"contentHash"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+const revisionColumns = "id, identity, revision, name, coalesce(content_hash, ''), created_at"
+
+func scanRevisions(rows *sql.Rows) ([]Revision, error) {
+	defer rows.Close()
+	result := []Revision{}
+	for rows.Next() {
+		r := Revision{}
+		if err := rows.Scan(&r.Id, &r.Identity, &r.Revision, &r.Name, &r.ContentHash, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func (s *sqlStore) Revisions(id int) ([]Revision, error) {
+	var identity string
+	err := s.Db.QueryRow("select identity from graph where id = $1", id).Scan(&identity)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query("select "+revisionColumns+" from graph where identity = $1 order by revision", identity)
+	if err != nil {
+		return nil, err
+	}
+	return scanRevisions(rows)
+}
+
+func (s *sqlStore) ResolveRevision(id int, revision int) (int, error) {
+	var resolved int
+	err := s.Db.QueryRow(`select id from graph
+		where identity = (select identity from graph where id = $1) and revision = $2`, id, revision).Scan(&resolved)
+	return resolved, err
+}
+
+func (s *sqlStore) LatestRevision(id int) (int, error) {
+	var latest int
+	err := s.Db.QueryRow(`select id from graph
+		where identity = (select identity from graph where id = $1) order by revision desc limit 1`, id).Scan(&latest)
+	return latest, err
+}
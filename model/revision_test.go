@@ -0,0 +1,62 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_Revisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("select identity from graph where id = \\$1").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"identity"}).AddRow("graph-1"))
+	mock.ExpectQuery("select id, identity, revision, name, (.+) from graph where identity = \\$1 order by revision").
+		WithArgs("graph-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "identity", "revision", "name", "content_hash", "created_at"}).
+			AddRow(1, "graph-1", 1, "Test Graph", "aaa", created).
+			AddRow(4, "graph-1", 2, "Test Graph", "bbb", created.Add(time.Hour)))
+
+	revisions, err := NewPostgresStore(db).Revisions(4)
+	assert.NoError(t, err)
+	assert.Equal(t, []Revision{
+		{Id: 1, Identity: "graph-1", Revision: 1, Name: "Test Graph", ContentHash: "aaa", CreatedAt: created},
+		{Id: 4, Identity: "graph-1", Revision: 2, Name: "Test Graph", ContentHash: "bbb", CreatedAt: created.Add(time.Hour)},
+	}, revisions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph_RevisionsUnknownGraph(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select identity from graph where id = \\$1").
+		WithArgs(9).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = NewPostgresStore(db).Revisions(9)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGraph_ResolveRevision(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("select id from graph").
+		WithArgs(4, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	id, err := NewPostgresStore(db).ResolveRevision(4, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
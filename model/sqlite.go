@@ -0,0 +1,131 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteSchema mirrors the Postgres schema built by the migrations.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS graph (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    identity varchar,
+    name varchar,
+    content_hash varchar,
+    revision integer NOT NULL DEFAULT 1,
+    created_at timestamp NOT NULL DEFAULT current_timestamp,
+    last_used_at timestamp NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT graph_revision_key UNIQUE (identity, revision)
+);
+CREATE INDEX IF NOT EXISTS graph_identity_content_hash_idx ON graph (identity, content_hash);
+CREATE TABLE IF NOT EXISTS node (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    identity varchar NOT NULL,
+    name varchar NOT NULL,
+    graph_id integer NOT NULL REFERENCES graph(id) ON DELETE CASCADE,
+    removed_in integer,
+    CONSTRAINT node_key UNIQUE (identity, graph_id)
+);
+CREATE TABLE IF NOT EXISTS edge (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    identity varchar,
+    from_id integer NOT NULL REFERENCES node(id) ON DELETE CASCADE,
+    from_identity varchar NOT NULL,
+    to_id integer NOT NULL REFERENCES node(id) ON DELETE CASCADE,
+    to_identity varchar NOT NULL,
+    cost real NOT NULL,
+    graph_id integer NOT NULL REFERENCES graph(id) ON DELETE CASCADE,
+    removed_in integer,
+    CONSTRAINT edge_key UNIQUE (identity, graph_id),
+    CONSTRAINT edge_key2 UNIQUE (from_id, to_id, graph_id)
+);`
+
+// SQLiteStore is the GraphStore backed by an SQLite file, for running without
+// a Postgres server.
+type SQLiteStore struct {
+	*sqlStore
+}
+
+// OpenSQLite opens or creates the SQLite database at path and creates the
+// schema. The path ":memory:" keeps the database in memory.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if path == ":memory:" {
+		path = "file::memory:"
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection also keeps an in-memory
+	// database alive and shared.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := upgradeSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{&sqlStore{Db: db, dialect: dialect{
+		constraintError: sqliteConstraintError,
+		timeArg: func(t time.Time) interface{} {
+			// current_timestamp is stored as UTC text in this layout
+			return t.UTC().Format("2006-01-02 15:04:05")
+		},
+	}}}, nil
+}
+
+// upgradeSQLite adds the removed_in columns to databases created before
+// revisions shared their nodes and edges, like migration 000005 does for Postgres.
+func upgradeSQLite(db *sql.DB) error {
+	for _, table := range []string{"node", "edge"} {
+		var n int
+		if err := db.QueryRow("select count(*) from pragma_table_info('" + table + "') where name = 'removed_in'").Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec("alter table " + table + " add column removed_in integer"); err != nil {
+			return err
+		}
+		// revisions saved so far hold full copies, so their rows end with the next revision
+		_, err := db.Exec(`update ` + table + ` set removed_in = (select g.revision + 1 from graph g where g.id = ` + table + `.graph_id)
+			where exists (select 1 from graph g join graph l on l.identity = g.identity and l.revision > g.revision where g.id = ` + table + `.graph_id)`)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.Db.Close()
+}
+
+// sqliteConstraintError translates unique constraint violations into model errors.
+func sqliteConstraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+	msg := sqliteErr.Error()
+	switch {
+	case strings.Contains(msg, "node.identity"):
+		return ErrDuplicateNode
+	case strings.Contains(msg, "edge.identity"):
+		return ErrDuplicateEdge
+	case strings.Contains(msg, "edge.from_id"):
+		return ErrDuplicateEdgeNodes
+	case strings.Contains(msg, "graph.identity"):
+		return ErrRevisionConflict
+	}
+	return err
+}
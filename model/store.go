@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// GraphStore persists graphs with their nodes and edges. Every revision of a
+// graph is stored as a graph of its own with its own id; revisions share the
+// graph identity. Lookups of graphs, nodes or edges that do not exist return
+// sql.ErrNoRows, and node and edge mutations return the errors in errors.go
+// when they would break a graph rule.
+//
+// A stored revision never changes. Node and edge mutations save the change as
+// the next revision of the identity of graph graphId and return the id of that
+// revision, so queries against a revision always give the same answers. Only the
+// latest revision can be changed: a mutation of an older one returns
+// ErrNotLatestRevision, and one racing another change returns ErrRevisionConflict.
+type GraphStore interface {
+	// Create saves g as the next revision of its identity and sets the ids and
+	// revision fields of g, its nodes and edges.
+	Create(g *Graph) error
+	// Import is Create unless the latest revision with the same identity has the
+	// same content hash, in which case g is pointed at that revision instead and
+	// the revision is marked as used.
+	Import(g *Graph) (created bool, err error)
+	// Get loads graph id with its nodes and edges.
+	Get(id int) (*Graph, error)
+	// List describes every stored graph ordered by identity and revision.
+	List() ([]Revision, error)
+	// Delete removes every revision sharing the identity of graph id, with their
+	// nodes and edges, and returns the ids of the removed revisions.
+	Delete(id int) ([]int, error)
+
+	// Revisions lists every revision sharing the identity of graph id, oldest first.
+	Revisions(id int) ([]Revision, error)
+	// ResolveRevision returns the id of the given revision of the graph id belongs to.
+	ResolveRevision(id int, revision int) (int, error)
+	// LatestRevision returns the id of the newest revision of the graph id belongs to.
+	LatestRevision(id int) (int, error)
+
+	// MarkUsed records that graph id was just queried.
+	MarkUsed(id int) error
+	// PurgeStale deletes every graph none of whose revisions was queried or
+	// updated since before, except the graphs with a revision in keep, and
+	// returns the ids of the deleted revisions. Graphs are purged with all their
+	// revisions, so no history is left with gaps.
+	PurgeStale(before time.Time, keep []int) ([]int, error)
+
+	// FindCycles returns the elementary cycles of graph id, each as a list of
+	// node identities, at most limit of them when limit is positive.
+	FindCycles(id int, limit int) ([][]string, error)
+	// TopoSort returns a topological order of graph id, or one of its cycles when
+	// it has any.
+	TopoSort(id int) (TopoOrder, error)
+
+	ListNodes(graphId int) ([]Node, error)
+	GetNode(graphId int, identity string) (Node, error)
+	// CreateNode adds n to the graph and sets n.Id.
+	CreateNode(graphId int, n *Node) (int, error)
+	// UpdateNode renames the node with identity n.Identity and sets n.Id.
+	UpdateNode(graphId int, n *Node) (int, error)
+	// DeleteNode removes a node together with every edge starting or ending at it.
+	DeleteNode(graphId int, identity string) (int, error)
+
+	ListEdges(graphId int) ([]Edge, error)
+	GetEdge(graphId int, identity string) (Edge, error)
+	// CreateEdge adds e to the graph. Both end nodes must exist and the cost must
+	// be non-negative. e.Id, e.FromId and e.ToId are set on success.
+	CreateEdge(graphId int, e *Edge) (int, error)
+	// UpdateEdge changes the cost of the edge with identity e.Identity and fills
+	// in the remaining fields of e.
+	UpdateEdge(graphId int, e *Edge) (int, error)
+	DeleteEdge(graphId int, identity string) (int, error)
+}
+
+var (
+	_ GraphStore = (*PostgresStore)(nil)
+	_ GraphStore = (*SQLiteStore)(nil)
+	_ GraphStore = (*MemoryStore)(nil)
+)
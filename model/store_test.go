@@ -0,0 +1,250 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GuohaoMa/tucowDemo/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeBackends returns a fresh instance of every store that runs without
+// external services, and of the Postgres store when TEST_DATABASE_URL names a
+// database the tests may empty.
+func storeBackends(t *testing.T) map[string]GraphStore {
+	t.Helper()
+	sqliteStore, err := OpenSQLite(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteStore.Close() })
+	stores := map[string]GraphStore{
+		"memory": NewMemoryStore(),
+		"sqlite": sqliteStore,
+	}
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		db, err := sql.Open("postgres", url)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, (&database.Database{Db: db}).Migrate("../migrations"))
+		_, err = db.Exec("truncate graph, node, edge")
+		require.NoError(t, err)
+		stores["postgres"] = NewPostgresStore(db)
+	}
+	return stores
+}
+
+func testGraph() *Graph {
+	return &Graph{
+		Identity: "graph-1",
+		Name:     "Test Graph",
+		Nodes: []Node{
+			{Identity: "a", Name: "A"},
+			{Identity: "b", Name: "B"},
+			{Identity: "c", Name: "C"},
+		},
+		Edges: []Edge{
+			{Identity: "e1", FromIdentity: "a", ToIdentity: "b", Cost: 1.5},
+			{Identity: "e2", FromIdentity: "b", ToIdentity: "c", Cost: 2},
+			{Identity: "e3", FromIdentity: "c", ToIdentity: "a", Cost: 0},
+		},
+	}
+}
+
+func TestGraphStore_Conformance(t *testing.T) {
+	for name, store := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			g := testGraph()
+			created, err := store.Import(g)
+			require.NoError(t, err)
+			assert.True(t, created)
+			assert.Equal(t, 1, g.Revision)
+
+			again := testGraph()
+			created, err = store.Import(again)
+			require.NoError(t, err)
+			assert.False(t, created)
+			assert.Equal(t, g.Id, again.Id)
+
+			loaded, err := store.Get(g.Id)
+			require.NoError(t, err)
+			assert.Equal(t, "Test Graph", loaded.Name)
+			assert.Len(t, loaded.Nodes, 3)
+			assert.Len(t, loaded.Edges, 3)
+			assert.Equal(t, g.Hash(), loaded.Hash())
+
+			cycles, err := store.FindCycles(g.Id, 0)
+			require.NoError(t, err)
+			assert.Equal(t, [][]string{{"a", "b", "c"}}, cycles)
+			order, err := store.TopoSort(g.Id)
+			require.NoError(t, err)
+			assert.Equal(t, TopoOrder{Cycle: []string{"a", "b", "c"}}, order)
+
+			// nodes and edges, every change saved as a new revision
+			_, err = store.CreateNode(g.Id, &Node{Identity: "a", Name: "Again"})
+			assert.ErrorIs(t, err, ErrDuplicateNode)
+			d := Node{Identity: "d", Name: "D"}
+			id, err := store.CreateNode(g.Id, &d)
+			require.NoError(t, err)
+			assert.NotZero(t, d.Id)
+			assert.NotEqual(t, g.Id, id)
+			withD := id
+			id, err = store.UpdateNode(id, &Node{Identity: "d", Name: "Renamed"})
+			require.NoError(t, err)
+			node, err := store.GetNode(id, "d")
+			require.NoError(t, err)
+			assert.Equal(t, "Renamed", node.Name)
+			node, err = store.GetNode(withD, "d")
+			require.NoError(t, err)
+			assert.Equal(t, "D", node.Name)
+			_, err = store.GetNode(g.Id, "d")
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			_, err = store.CreateEdge(id, &Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "x"})
+			assert.ErrorIs(t, err, ErrUnknownNode)
+			_, err = store.CreateEdge(id, &Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "d", Cost: -1})
+			assert.ErrorIs(t, err, ErrNegativeCost)
+			_, err = store.CreateEdge(id, &Edge{Identity: "e1", FromIdentity: "a", ToIdentity: "d"})
+			assert.ErrorIs(t, err, ErrDuplicateEdge)
+			_, err = store.CreateEdge(id, &Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "b"})
+			assert.ErrorIs(t, err, ErrDuplicateEdgeNodes)
+			e4 := Edge{Identity: "e4", FromIdentity: "a", ToIdentity: "d", Cost: 3}
+			id, err = store.CreateEdge(id, &e4)
+			require.NoError(t, err)
+			node, err = store.GetNode(id, "d")
+			require.NoError(t, err)
+			assert.Equal(t, node.Id, e4.ToId)
+
+			update := Edge{Identity: "e4", Cost: 4}
+			withE4 := id
+			id, err = store.UpdateEdge(id, &update)
+			require.NoError(t, err)
+			assert.Equal(t, "a", update.FromIdentity)
+			assert.Equal(t, 4.0, update.Cost)
+			edge, err := store.GetEdge(withE4, "e4")
+			require.NoError(t, err)
+			assert.Equal(t, 3.0, edge.Cost)
+
+			id, err = store.DeleteNode(id, "d")
+			require.NoError(t, err)
+			_, err = store.GetEdge(id, "e4")
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			id, err = store.DeleteEdge(id, "e3")
+			require.NoError(t, err)
+			_, err = store.DeleteEdge(id, "e3")
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			edges, err := store.ListEdges(id)
+			require.NoError(t, err)
+			assert.Len(t, edges, 2)
+			nodes, err := store.ListNodes(id)
+			require.NoError(t, err)
+			assert.Equal(t, "a", nodes[0].Identity)
+			edges, err = store.ListEdges(withE4)
+			require.NoError(t, err)
+			assert.Len(t, edges, 4)
+
+			// only the latest revision can be changed
+			_, err = store.CreateNode(g.Id, &Node{Identity: "x", Name: "X"})
+			assert.ErrorIs(t, err, ErrNotLatestRevision)
+			_, err = store.DeleteEdge(withE4, "e4")
+			assert.ErrorIs(t, err, ErrNotLatestRevision)
+			latest, err := store.LatestRevision(g.Id)
+			require.NoError(t, err)
+			assert.Equal(t, id, latest)
+
+			// the first revision is untouched and failed changes saved nothing
+			loaded, err = store.Get(g.Id)
+			require.NoError(t, err)
+			assert.Equal(t, g.Hash(), loaded.Hash())
+			revisions, err := store.Revisions(g.Id)
+			require.NoError(t, err)
+			assert.Len(t, revisions, 7)
+			assert.Equal(t, id, revisions[6].Id)
+
+			// a modified graph is no longer matched by its import hash
+			third := testGraph()
+			created, err = store.Import(third)
+			require.NoError(t, err)
+			assert.True(t, created)
+			assert.Equal(t, 8, third.Revision)
+			loaded, err = store.Get(third.Id)
+			require.NoError(t, err)
+			assert.Equal(t, g.Hash(), loaded.Hash())
+
+			revisions, err = store.Revisions(g.Id)
+			require.NoError(t, err)
+			assert.Len(t, revisions, 8)
+			id, err = store.ResolveRevision(g.Id, 8)
+			require.NoError(t, err)
+			assert.Equal(t, third.Id, id)
+			_, err = store.ResolveRevision(g.Id, 9)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			list, err := store.List()
+			require.NoError(t, err)
+			assert.Len(t, list, 8)
+
+			// retention and deletion work on every revision of a graph
+			other := testGraph()
+			other.Identity = "graph-2"
+			require.NoError(t, store.Create(other))
+			deleted, err := store.PurgeStale(time.Now().Add(time.Hour), []int{g.Id})
+			require.NoError(t, err)
+			assert.Equal(t, []int{other.Id}, deleted)
+			_, err = store.Get(third.Id)
+			require.NoError(t, err)
+			require.NoError(t, store.MarkUsed(third.Id))
+			deleted, err = store.Delete(g.Id)
+			require.NoError(t, err)
+			assert.Len(t, deleted, 8)
+			assert.Contains(t, deleted, third.Id)
+			_, err = store.Delete(third.Id)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			_, err = store.ListNodes(third.Id)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		})
+	}
+}
+
+func TestGraphStore_ConcurrentCreates(t *testing.T) {
+	for name, store := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			errs := make([]error, 8)
+			for i := range errs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					g := testGraph()
+					g.Identity = "concurrent"
+					errs[i] = store.Create(g)
+				}()
+			}
+			wg.Wait()
+
+			created := 0
+			for _, err := range errs {
+				if err == nil {
+					created++
+				} else if !errors.Is(err, ErrRevisionConflict) {
+					t.Errorf("unexpected error %v", err)
+				}
+			}
+			list, err := store.List()
+			require.NoError(t, err)
+			revisions := []int{}
+			for _, r := range list {
+				if r.Identity == "concurrent" {
+					revisions = append(revisions, r.Revision)
+				}
+			}
+			assert.Len(t, revisions, created)
+			for i, r := range revisions {
+				assert.Equal(t, i+1, r)
+			}
+		})
+	}
+}
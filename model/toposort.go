@@ -0,0 +1,58 @@
+package model
+
+import "sort"
+
+// TopoOrder is the result of Index.TopoSort. Either Cycle is set or Order and
+// Levels are.
+type TopoOrder struct {
+	// Order lists every node so that edges only lead to later nodes.
+	Order []string
+	// Levels groups the nodes of Order: the first level holds the nodes without
+	// incoming edges and every further one the nodes whose predecessors all sit
+	// on earlier levels. Nodes of one level do not depend on each other.
+	Levels [][]string
+	// Cycle is one cycle of a graph that has no topological order, in the form
+	// Cycles reports it.
+	Cycle []string
+}
+
+// TopoSort orders the graph with Kahn's algorithm, level by level and by
+// identity within a level, so the order is always the same. A self-loop is a
+// cycle of its own node; it is reported when the graph has no longer cycle.
+func (ix *Index) TopoSort() TopoOrder {
+	indegree := make(map[string]int, len(ix.Nodes))
+	for n := range ix.Nodes {
+		indegree[n] = len(ix.In[n])
+	}
+	level := []string{}
+	for n, d := range indegree {
+		if d == 0 {
+			level = append(level, n)
+		}
+	}
+
+	result := TopoOrder{Order: make([]string, 0, len(ix.Nodes)), Levels: [][]string{}}
+	for len(level) > 0 {
+		sort.Strings(level)
+		result.Order = append(result.Order, level...)
+		result.Levels = append(result.Levels, level)
+		next := []string{}
+		for _, n := range level {
+			for _, a := range ix.Out[n] {
+				if indegree[a.To]--; indegree[a.To] == 0 {
+					next = append(next, a.To)
+				}
+			}
+		}
+		level = next
+	}
+
+	// nodes left over wait on each other
+	if len(result.Order) < len(ix.Nodes) {
+		return TopoOrder{Cycle: elementaryCycles(ix.Out, 1)[0]}
+	}
+	if loops := ix.selfLoops(); len(loops) > 0 {
+		return TopoOrder{Cycle: loops[:1]}
+	}
+	return result
+}
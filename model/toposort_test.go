@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_TopoSort(t *testing.T) {
+	g := &Graph{Nodes: []Node{{Identity: "e"}, {Identity: "d"}, {Identity: "c"}, {Identity: "b"}, {Identity: "a"}, {Identity: "lone"}}}
+	for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}, {"d", "e"}, {"a", "e"}} {
+		g.Edges = append(g.Edges, Edge{Identity: e[0] + e[1], FromIdentity: e[0], ToIdentity: e[1]})
+	}
+
+	assert.Equal(t, TopoOrder{
+		Order:  []string{"a", "lone", "b", "c", "d", "e"},
+		Levels: [][]string{{"a", "lone"}, {"b", "c"}, {"d"}, {"e"}},
+	}, NewIndex(g).TopoSort())
+
+	// a self-loop is a cycle, reported when there is no longer one
+	g.Edges = append(g.Edges, Edge{Identity: "bb", FromIdentity: "b", ToIdentity: "b"}, Edge{Identity: "ee", FromIdentity: "e", ToIdentity: "e"})
+	assert.Equal(t, TopoOrder{Cycle: []string{"b"}}, NewIndex(g).TopoSort())
+
+	g.Edges = append(g.Edges, Edge{Identity: "ec", FromIdentity: "e", ToIdentity: "c"})
+	assert.Equal(t, TopoOrder{Cycle: []string{"c", "d", "e"}}, NewIndex(g).TopoSort())
+}
+
+func TestIndex_TopoSortEmpty(t *testing.T) {
+	assert.Equal(t, TopoOrder{Order: []string{}, Levels: [][]string{}}, NewIndex(&Graph{}).TopoSort())
+}
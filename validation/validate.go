@@ -2,35 +2,80 @@ package validation
 
 import (
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
-	"os"
-
-	"github.com/GuohaoMa/tucowDemo/model"
+	"strings"
 )
 
-func Validate(filePath string) error {
-	xmlFile, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("error opening XML file: %v", err)
+// Violation is a single broken rule, located at the element that caused it.
+type Violation struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", v.Line, v.Column, v.Message)
+}
+
+// Report collects every violation found in a graph document. A non-empty report
+// is returned as the error of Validate so callers can render it with errors.As.
+type Report struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Report) add(line, column int, message string) {
+	r.Violations = append(r.Violations, Violation{Line: line, Column: column, Message: message})
+}
+
+// Valid reports whether no violation was found.
+func (r *Report) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+func (r *Report) Error() string {
+	lines := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		lines[i] = v.String()
 	}
-	defer xmlFile.Close()
-	decoder := xml.NewDecoder(xmlFile)
+	return strings.Join(lines, "\n")
+}
+
+// edgeElement decodes <from> and <to> as slices so repeated tags can be detected.
+type edgeElement struct {
+	Identity string   `xml:"id"`
+	From     []string `xml:"from"`
+	To       []string `xml:"to"`
+	Cost     float64  `xml:"cost"`
+}
+
+type nodeElement struct {
+	Identity string `xml:"id"`
+	Name     string `xml:"name"`
+}
+
+// Validate checks the XML graph document read from r against the graph rules.
+// All violations are collected and returned together as a *Report.
+func Validate(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	report := &Report{}
 
 	nodeCount := 0
 	inNodes, inEdges := false, false
 	edgeElementFound := false
 	nodeIdMap := make(map[string]string)
-	fromInEdgeCount, inInEdgeCount := 0, 0
+	edges := edgeSet{ids: make(map[string]bool), ends: make(map[[2]string]bool)}
 	idInGraphCount, nameInGraphCount := 0, 0
 	for {
+		line, column := decoder.InputPos()
 		t, err := decoder.Token()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("Error decoding XML: %v", err)
+			line, column = decoder.InputPos()
+			report.add(line, column, fmt.Sprintf("Error decoding XML: %v", err))
+			return report
 		}
 
 		switch elem := t.(type) {
@@ -38,7 +83,7 @@ func Validate(filePath string) error {
 			if elem.Name.Local == "nodes" {
 				inNodes = true
 				if edgeElementFound {
-					return errors.New("The <nodes> group must come before the <edges> group.")
+					report.add(line, column, "The <nodes> group must come before the <edges> group.")
 				}
 			}
 			if elem.Name.Local == "edges" {
@@ -48,67 +93,35 @@ func Validate(filePath string) error {
 			if elem.Name.Local == "node" {
 				if inNodes == true && inEdges == false {
 					nodeCount += 1
-					var node model.Node
-					decoder.DecodeElement(&node, &elem)
+					var node nodeElement
+					if err := decoder.DecodeElement(&node, &elem); err != nil {
+						report.add(line, column, fmt.Sprintf("Error decoding XML: %v", err))
+						return report
+					}
 					if _, ok := nodeIdMap[node.Identity]; ok {
-						return errors.New("All nodes must have different <id> tags.")
+						report.add(line, column, "All nodes must have different <id> tags.")
 					} else {
 						nodeIdMap[node.Identity] = node.Name
 					}
 				}
 				if inEdges == true && inNodes == false {
-					if nodeCount == 0 {
-						return errors.New("There must be at least one <node> in the <nodes> group")
-					}
-					var edge model.Edge
-					decoder.DecodeElement(&edge, &elem)
-					if edge.FromIdentity == "" {
-						return errors.New("For every <edge>, there must be a single <from> tag")
-					}
-					if edge.ToIdentity == "" {
-						return errors.New("For every <edge>, there must be a single <to> tag")
-					}
-					if _, ok := nodeIdMap[edge.FromIdentity]; !ok {
-						return errors.New("From node of an edge must be predefined.")
-					}
-					if _, ok := nodeIdMap[edge.ToIdentity]; !ok {
-						return errors.New("To node of an edge must be predefined.")
-					}
-					if edge.Cost < 0 {
-						return errors.New("Cost of an edge must be non-negative.")
-					}
-				}
-			}
-			if elem.Name.Local == "from" {
-				if inEdges == true {
-					if fromInEdgeCount == 0 {
-						fromInEdgeCount += 1
-					} else {
-						return errors.New("For every <edge>, there must be a single <from> tag")
-					}
-				}
-			}
-			if elem.Name.Local == "to" {
-				if inEdges == true {
-					if inInEdgeCount == 0 {
-						inInEdgeCount += 1
-					} else {
-						return errors.New("For every <edge>, there must be a single <to> tag")
+					var edge edgeElement
+					if err := decoder.DecodeElement(&edge, &elem); err != nil {
+						report.add(line, column, fmt.Sprintf("Error decoding XML: %v", err))
+						return report
 					}
+					validateEdge(report, line, column, edge, nodeIdMap)
+					edges.check(report, line, column, edge)
 				}
 			}
 			if elem.Name.Local == "id" {
 				if inNodes == false && inEdges == false {
-					if idInGraphCount == 0 {
-						idInGraphCount += 1
-					}
+					idInGraphCount += 1
 				}
 			}
 			if elem.Name.Local == "name" {
 				if inNodes == false && inEdges == false {
-					if nameInGraphCount == 0 {
-						nameInGraphCount += 1
-					}
+					nameInGraphCount += 1
 				}
 			}
 		case xml.EndElement:
@@ -118,21 +131,60 @@ func Validate(filePath string) error {
 			if elem.Name.Local == "edges" {
 				inEdges = false
 			}
-			if elem.Name.Local == "node" && inEdges == true {
-				fromInEdgeCount = 0
-				inInEdgeCount = 0
-			}
 		}
 	}
 
+	line, column := decoder.InputPos()
 	if nodeCount == 0 {
-		return errors.New("There must be at least one <node> in the <nodes> group")
+		report.add(line, column, "There must be at least one <node> in the <nodes> group")
 	}
 	if idInGraphCount == 0 {
-		return errors.New("There must be an <id> in the <graph>")
+		report.add(line, column, "There must be an <id> in the <graph>")
 	}
 	if nameInGraphCount == 0 {
-		return errors.New("There must be an <name> in the <graph>")
+		report.add(line, column, "There must be an <name> in the <graph>")
+	}
+	if !report.Valid() {
+		return report
 	}
 	return nil
 }
+
+// edgeSet remembers the edges seen so far to report repeated ids and node pairs.
+type edgeSet struct {
+	ids  map[string]bool
+	ends map[[2]string]bool
+}
+
+func (s edgeSet) check(report *Report, line, column int, edge edgeElement) {
+	if edge.Identity != "" {
+		if s.ids[edge.Identity] {
+			report.add(line, column, "All edges must have different <id> tags.")
+		}
+		s.ids[edge.Identity] = true
+	}
+	if len(edge.From) != 1 || len(edge.To) != 1 {
+		return
+	}
+	ends := [2]string{edge.From[0], edge.To[0]}
+	if s.ends[ends] {
+		report.add(line, column, "There can be only one edge between the same <from> and <to> nodes.")
+	}
+	s.ends[ends] = true
+}
+
+func validateEdge(report *Report, line, column int, edge edgeElement, nodeIdMap map[string]string) {
+	if len(edge.From) != 1 || edge.From[0] == "" {
+		report.add(line, column, "For every <edge>, there must be a single <from> tag")
+	} else if _, ok := nodeIdMap[edge.From[0]]; !ok {
+		report.add(line, column, "From node of an edge must be predefined.")
+	}
+	if len(edge.To) != 1 || edge.To[0] == "" {
+		report.add(line, column, "For every <edge>, there must be a single <to> tag")
+	} else if _, ok := nodeIdMap[edge.To[0]]; !ok {
+		report.add(line, column, "To node of an edge must be predefined.")
+	}
+	if edge.Cost < 0 {
+		report.add(line, column, "Cost of an edge must be non-negative.")
+	}
+}
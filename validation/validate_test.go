@@ -1,26 +1,23 @@
 package validation
 
 import (
-	"os"
+	"errors"
+	"strings"
 	"testing"
 )
 
-func createTempFile(t *testing.T, content string) *os.File {
-	t.Helper()
-	tmpfile, err := os.CreateTemp("", "test*.xml")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-
-	if _, err := tmpfile.Write([]byte(content)); err != nil {
-		t.Fatalf("Failed to write to temp file: %v", err)
+// hasViolation reports whether err is a *Report containing the given message.
+func hasViolation(err error, message string) bool {
+	var report *Report
+	if !errors.As(err, &report) {
+		return false
 	}
-
-	if err := tmpfile.Close(); err != nil {
-		t.Fatalf("Failed to close temp file: %v", err)
+	for _, v := range report.Violations {
+		if v.Message == message {
+			return true
+		}
 	}
-
-	return tmpfile
+	return false
 }
 
 func TestValidate_ValidXML(t *testing.T) {
@@ -48,10 +45,7 @@ func TestValidate_ValidXML(t *testing.T) {
 		</edges>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
+	err := Validate(strings.NewReader(xmlContent))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -73,11 +67,8 @@ func TestValidate_MissingNodes(t *testing.T) {
 		</edges>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "There must be at least one <node> in the <nodes> group" {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "There must be at least one <node> in the <nodes> group") {
 		t.Errorf("Expected error about missing nodes, got %v", err)
 	}
 }
@@ -95,11 +86,8 @@ func TestValidate_MissingGraphID(t *testing.T) {
 		</nodes>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "There must be an <id> in the <graph>" {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "There must be an <id> in the <graph>") {
 		t.Errorf("Expected error about missing graph ID, got %v", err)
 	}
 }
@@ -117,11 +105,8 @@ func TestValidate_MissingGraphName(t *testing.T) {
 		</nodes>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "There must be an <name> in the <graph>" {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "There must be an <name> in the <graph>") {
 		t.Errorf("Expected error about missing graph name, got %v", err)
 	}
 }
@@ -144,15 +129,38 @@ func TestValidate_DuplicateNodeID(t *testing.T) {
 		</nodes>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "All nodes must have different <id> tags." {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "All nodes must have different <id> tags.") {
 		t.Errorf("Expected error about duplicate node IDs, got %v", err)
 	}
 }
 
+func TestValidate_DuplicateEdges(t *testing.T) {
+	t.Parallel()
+	xmlContent := `
+	<graph>
+		<id>1</id>
+		<name>Test Graph</name>
+		<nodes>
+			<node><id>a</id><name>A</name></node>
+			<node><id>b</id><name>B</name></node>
+		</nodes>
+		<edges>
+			<node><id>e1</id><from>a</from><to>b</to><cost>1</cost></node>
+			<node><id>e1</id><from>b</from><to>a</to><cost>1</cost></node>
+			<node><id>e2</id><from>a</from><to>b</to><cost>2</cost></node>
+		</edges>
+	</graph>`
+
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "All edges must have different <id> tags.") {
+		t.Errorf("Expected error about duplicate edge IDs, got %v", err)
+	}
+	if !hasViolation(err, "There can be only one edge between the same <from> and <to> nodes.") {
+		t.Errorf("Expected error about duplicate edge nodes, got %v", err)
+	}
+}
+
 func TestValidate_NegativeEdgeCost(t *testing.T) {
 	t.Parallel()
 	xmlContent := `
@@ -178,11 +186,8 @@ func TestValidate_NegativeEdgeCost(t *testing.T) {
 		</edges>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "Cost of an edge must be non-negative." {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "Cost of an edge must be non-negative.") {
 		t.Errorf("Expected error about negative edge cost, got %v", err)
 	}
 }
@@ -211,11 +216,8 @@ func TestValidate_MissingEdgeFrom(t *testing.T) {
 		</edges>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "For every <edge>, there must be a single <from> tag" {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "For every <edge>, there must be a single <from> tag") {
 		t.Errorf("Expected error about missing <from> tag, got %v", err)
 	}
 }
@@ -244,11 +246,71 @@ func TestValidate_MissingEdgeTo(t *testing.T) {
 		</edges>
 	</graph>`
 
-	tmpfile := createTempFile(t, xmlContent)
-	defer os.Remove(tmpfile.Name())
-
-	err := Validate(tmpfile.Name())
-	if err == nil || err.Error() != "For every <edge>, there must be a single <to> tag" {
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "For every <edge>, there must be a single <to> tag") {
 		t.Errorf("Expected error about missing <to> tag, got %v", err)
 	}
 }
+
+func TestValidate_CollectsAllViolations(t *testing.T) {
+	t.Parallel()
+	xmlContent := `<graph>
+	<id>1</id>
+	<name>Test Graph</name>
+	<nodes>
+		<node><id>1</id><name>Node1</name></node>
+		<node><id>1</id><name>Node1 again</name></node>
+	</nodes>
+	<edges>
+		<node><from>1</from><to>3</to><cost>10</cost></node>
+		<node><from>1</from><from>1</from><to>1</to><cost>-1</cost></node>
+	</edges>
+</graph>`
+
+	err := Validate(strings.NewReader(xmlContent))
+	var report *Report
+	if !errors.As(err, &report) {
+		t.Fatalf("Expected a validation report, got %v", err)
+	}
+
+	expected := []Violation{
+		{Line: 6, Column: 3, Message: "All nodes must have different <id> tags."},
+		{Line: 9, Column: 3, Message: "To node of an edge must be predefined."},
+		{Line: 10, Column: 3, Message: "For every <edge>, there must be a single <from> tag"},
+		{Line: 10, Column: 3, Message: "Cost of an edge must be non-negative."},
+	}
+	if len(report.Violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %v", len(expected), report.Violations)
+	}
+	for i, v := range expected {
+		if report.Violations[i] != v {
+			t.Errorf("Expected violation %v, got %v", v, report.Violations[i])
+		}
+	}
+}
+
+func TestValidate_NodesAfterEdges(t *testing.T) {
+	t.Parallel()
+	xmlContent := `<graph>
+	<id>1</id>
+	<name>Test Graph</name>
+	<edges></edges>
+	<nodes>
+		<node><id>1</id><name>Node1</name></node>
+	</nodes>
+</graph>`
+
+	err := Validate(strings.NewReader(xmlContent))
+	if !hasViolation(err, "The <nodes> group must come before the <edges> group.") {
+		t.Errorf("Expected error about group ordering, got %v", err)
+	}
+}
+
+func TestValidate_MalformedXML(t *testing.T) {
+	t.Parallel()
+	err := Validate(strings.NewReader("<graph>\n<id>1</name>\n</graph>"))
+	var report *Report
+	if !errors.As(err, &report) || len(report.Violations) != 1 || report.Violations[0].Line != 2 {
+		t.Errorf("Expected a single decoding violation on line 2, got %v", err)
+	}
+}